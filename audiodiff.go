@@ -0,0 +1,137 @@
+package sf
+
+import (
+	"math"
+	"time"
+)
+
+// AudioDiffOptions configures CompareAudio.
+type AudioDiffOptions struct {
+	// SampleRate of the renders, used to report times. Defaults to 44100.
+	SampleRate int
+	// Window is the number of frames compared at a time, rounded up to a power
+	// of two. Defaults to 1024.
+	Window int
+	// RMSTolerance is the largest RMS of the difference signal, relative to
+	// full scale, a window may have before it counts as diverging. Defaults to
+	// 0.001 (-60 dBFS).
+	RMSTolerance float64
+	// SpectralTolerance is the largest log-spectral distance, in dB, a window
+	// may have before it counts as diverging. Zero disables the spectral check.
+	SpectralTolerance float64
+}
+
+// Divergence is a span of time where two renders differ beyond tolerance.
+type Divergence struct {
+	Start, End time.Duration
+	// RMS and Spectral are the worst values seen within the span.
+	RMS, Spectral float64
+}
+
+// AudioDiff is the result of comparing two renders.
+type AudioDiff struct {
+	// RMS of the difference over the whole render, relative to full scale.
+	RMS float64
+	// Peak is the largest absolute difference of any single frame.
+	Peak float64
+	// LengthDifference is len(b) - len(a) in frames. The shorter render is
+	// compared as if padded with silence.
+	LengthDifference int
+	Divergences      []Divergence
+}
+
+// Equal reports whether no window diverged.
+func (d AudioDiff) Equal() bool {
+	return len(d.Divergences) == 0
+}
+
+// CompareAudio compares two mono renders, samples in -1..1, window by window
+// and reports where they diverge. Compare multichannel renders one channel at a
+// time.
+func CompareAudio(a, b []float32, opts AudioDiffOptions) AudioDiff {
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 44100
+	}
+	if opts.Window <= 0 {
+		opts.Window = 1024
+	}
+	window := 1
+	for window < opts.Window {
+		window <<= 1
+	}
+	if opts.RMSTolerance <= 0 {
+		opts.RMSTolerance = 0.001
+	}
+
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	at := func(s []float32, i int) float64 {
+		if i < len(s) {
+			return float64(s[i])
+		}
+		return 0
+	}
+	toTime := func(frame int) time.Duration {
+		return time.Duration(frame) * time.Second / time.Duration(opts.SampleRate)
+	}
+
+	diff := AudioDiff{LengthDifference: len(b) - len(a)}
+	coeffs := hann(window)
+	fa, fb := make([]float64, window), make([]float64, window)
+
+	var total float64
+	var open *Divergence
+	for start := 0; start < n; start += window {
+		var sum float64
+		for i := 0; i < window; i++ {
+			fa[i], fb[i] = at(a, start+i), at(b, start+i)
+			d := fa[i] - fb[i]
+			sum += d * d
+			if math.Abs(d) > diff.Peak {
+				diff.Peak = math.Abs(d)
+			}
+		}
+		total += sum
+		rms := math.Sqrt(sum / float64(window))
+
+		var spectral float64
+		if opts.SpectralTolerance > 0 {
+			ma, mb := magnitudes(fa, coeffs), magnitudes(fb, coeffs)
+			var acc float64
+			for i := range ma {
+				d := 20*math.Log10(ma[i]+1e-9) - 20*math.Log10(mb[i]+1e-9)
+				acc += d * d
+			}
+			spectral = math.Sqrt(acc / float64(len(ma)))
+		}
+
+		diverged := rms > opts.RMSTolerance || (opts.SpectralTolerance > 0 && spectral > opts.SpectralTolerance)
+		if !diverged {
+			open = nil
+			continue
+		}
+
+		end := start + window
+		if end > n {
+			end = n
+		}
+		if open == nil {
+			diff.Divergences = append(diff.Divergences, Divergence{Start: toTime(start)})
+			open = &diff.Divergences[len(diff.Divergences)-1]
+		}
+		open.End = toTime(end)
+		if rms > open.RMS {
+			open.RMS = rms
+		}
+		if spectral > open.Spectral {
+			open.Spectral = spectral
+		}
+	}
+
+	if n > 0 {
+		diff.RMS = math.Sqrt(total / float64(n))
+	}
+	return diff
+}
@@ -0,0 +1,209 @@
+package sf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PresetRef is a preset found in a BankSet.
+type PresetRef struct {
+	// Font is the name the SoundFont was added under.
+	Font      string
+	SoundFont *SoundFont
+
+	// Preset is the preset's index in SoundFont.Hydra.Headers.
+	Preset int
+}
+
+type bankSetFont struct {
+	name     string
+	sf       *SoundFont
+	priority int
+	presets  map[[2]uint16]int // bank and program to header index
+}
+
+// BankSet routes bank and program lookups across several SoundFonts, so that
+// for example a drum font can sit on top of a General MIDI font without
+// merging the files. Fonts with a higher priority are searched first, fonts
+// of equal priority in the order they were added. Overrides route a single
+// bank and program to a chosen preset regardless of priority.
+type BankSet struct {
+	// Fallback decides what Resolve plays when a bank and program is
+	// missing.
+	Fallback FallbackPolicy
+
+	fonts     []*bankSetFont
+	overrides map[[2]uint16]override
+}
+
+type override struct {
+	font          string
+	bank, program uint16
+}
+
+// Add puts a SoundFont in the set under a name, replacing any font of the same
+// name.
+func (s *BankSet) Add(name string, sf *SoundFont, priority int) {
+	s.Remove(name)
+
+	f := &bankSetFont{name: name, sf: sf, priority: priority, presets: map[[2]uint16]int{}}
+	for i := 0; i+1 < len(sf.Hydra.Headers); i++ {
+		h := sf.Hydra.Headers[i]
+		k := [2]uint16{h.Bank, h.Preset}
+		// like a synth, the first of duplicate presets wins
+		if _, ok := f.presets[k]; !ok {
+			f.presets[k] = i
+		}
+	}
+
+	s.fonts = append(s.fonts, f)
+	sort.SliceStable(s.fonts, func(i, j int) bool { return s.fonts[i].priority > s.fonts[j].priority })
+}
+
+// Remove takes the named font out of the set. Overrides pointing at it stay
+// but no longer resolve.
+func (s *BankSet) Remove(name string) {
+	for i, f := range s.fonts {
+		if f.name == name {
+			s.fonts = append(s.fonts[:i], s.fonts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Override routes bank and program to the preset at srcBank and srcProgram
+// of the named font.
+func (s *BankSet) Override(bank, program uint16, font string, srcBank, srcProgram uint16) {
+	if s.overrides == nil {
+		s.overrides = map[[2]uint16]override{}
+	}
+	s.overrides[[2]uint16{bank, program}] = override{font, srcBank, srcProgram}
+}
+
+// ClearOverride removes the override of bank and program.
+func (s *BankSet) ClearOverride(bank, program uint16) {
+	delete(s.overrides, [2]uint16{bank, program})
+}
+
+func (s *BankSet) font(name string) *bankSetFont {
+	for _, f := range s.fonts {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Lookup finds the preset that plays for bank and program.
+func (s *BankSet) Lookup(bank, program uint16) (PresetRef, bool) {
+	if o, ok := s.overrides[[2]uint16{bank, program}]; ok {
+		if f := s.font(o.font); f != nil {
+			if i, ok := f.presets[[2]uint16{o.bank, o.program}]; ok {
+				return PresetRef{f.name, f.sf, i}, true
+			}
+		}
+	}
+
+	for _, f := range s.fonts {
+		if i, ok := f.presets[[2]uint16{bank, program}]; ok {
+			return PresetRef{f.name, f.sf, i}, true
+		}
+	}
+	return PresetRef{}, false
+}
+
+// FallbackRule is one step of a FallbackPolicy.
+type FallbackRule int
+
+const (
+	// Fallback_Bank0 plays the same program in bank 0, the General MIDI
+	// bank. Drum kits in bank 128 fall back to program 0 of bank 128, the
+	// standard kit, instead.
+	Fallback_Bank0 FallbackRule = iota
+	// Fallback_NearestBank plays the same program in the closest bank that
+	// has it.
+	Fallback_NearestBank
+	// Fallback_Silence stops looking, the channel stays silent.
+	Fallback_Silence
+)
+
+// FallbackPolicy lists the rules tried, in order, when a program change asks
+// for a bank and program no font has. Without rules nothing plays, as with
+// Fallback_Silence.
+type FallbackPolicy struct {
+	Rules []FallbackRule
+
+	// OnMissing, if set, is called for every miss with what the rules
+	// resolved to, if anything, for logging or to report an error.
+	OnMissing func(bank, program uint16, resolved PresetRef, ok bool)
+}
+
+// GMFallback is the policy most hardware modules follow: a missing variation
+// bank plays the program from bank 0.
+var GMFallback = FallbackPolicy{Rules: []FallbackRule{Fallback_Bank0}}
+
+// nearestBank finds program in the bank closest to bank, preferring lower
+// banks on ties and higher priority fonts within a bank.
+func (s *BankSet) nearestBank(bank, program uint16) (PresetRef, bool) {
+	best, bestDist := PresetRef{}, -1
+	for _, f := range s.fonts {
+		for k, i := range f.presets {
+			if k[1] != program {
+				continue
+			}
+			dist := int(k[0]) - int(bank)
+			if dist < 0 {
+				dist = -2 * dist
+			} else {
+				dist = 2*dist + 1
+			}
+			if bestDist < 0 || dist < bestDist {
+				best, bestDist = PresetRef{f.name, f.sf, i}, dist
+			}
+		}
+	}
+	return best, bestDist >= 0
+}
+
+// Resolve finds the preset to play for a program change, applying the
+// fallback policy when no font has bank and program.
+func (s *BankSet) Resolve(bank, program uint16) (PresetRef, bool) {
+	if ref, ok := s.Lookup(bank, program); ok {
+		return ref, true
+	}
+
+	ref, ok := PresetRef{}, false
+rules:
+	for _, rule := range s.Fallback.Rules {
+		switch rule {
+		case Fallback_Bank0:
+			if bank == 128 {
+				ref, ok = s.Lookup(128, 0)
+			} else {
+				ref, ok = s.Lookup(0, program)
+			}
+		case Fallback_NearestBank:
+			ref, ok = s.nearestBank(bank, program)
+		case Fallback_Silence:
+			break rules
+		}
+		if ok {
+			break
+		}
+	}
+
+	if s.Fallback.OnMissing != nil {
+		s.Fallback.OnMissing(bank, program, ref, ok)
+	}
+	return ref, ok
+}
+
+// Regions resolves the regions that play for a note on bank and program,
+// following the fallback policy.
+func (s *BankSet) Regions(bank, program uint16, key, vel uint8) ([]Region, error) {
+	ref, ok := s.Resolve(bank, program)
+	if !ok {
+		return nil, fmt.Errorf("no preset for bank %d program %d", bank, program)
+	}
+	return ref.SoundFont.Hydra.Regions(ref.Preset, key, vel)
+}
@@ -0,0 +1,139 @@
+package sf
+
+import "sort"
+
+// defaultAmount returns the value a generator has when no zone sets it. At
+// the preset level every generator but the ranges adds nothing.
+func defaultAmount(op SFGenerator, preset bool) int16 {
+	if op == Gen_KeyRange || op == Gen_VelRange {
+		return GeneratorDefaults[op]
+	}
+	if preset {
+		return 0
+	}
+	return GeneratorDefaults[op]
+}
+
+// dedupeGenerators keeps only the last of repeated generators in a zone, the
+// one that takes effect.
+func dedupeGenerators(gens []Generator) []Generator {
+	last := make(map[SFGenerator]int, len(gens))
+	for i, g := range gens {
+		last[g.GenOper] = i
+	}
+	out := gens[:0]
+	for i, g := range gens {
+		if last[g.GenOper] == i {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// removeRedundantGenerators drops generators that don't change anything: a
+// global zone's generators set to their default, and a local zone's
+// generators set to the value the zone would inherit anyway. A global zone
+// left empty is removed. terminal is the generator that ends local zones.
+func removeRedundantGenerators(zones []Zone, terminal SFGenerator) []Zone {
+	preset := terminal == Gen_Instrument
+	global := len(zones) > 0
+	if global {
+		_, isLocal := zones[0].terminal(terminal)
+		global = !isLocal
+	}
+
+	inherited := func(op SFGenerator) int16 {
+		if global {
+			for _, g := range zones[0].Generators {
+				if g.GenOper == op {
+					return g.GenAmount.AsInt16()
+				}
+			}
+		}
+		return defaultAmount(op, preset)
+	}
+
+	for i := range zones {
+		gens := dedupeGenerators(zones[i].Generators)
+		out := gens[:0]
+		for _, g := range gens {
+			redundant := false
+			if g.GenOper < Gen_EndOper && g.GenOper != terminal {
+				if i == 0 && global {
+					redundant = g.GenAmount.AsInt16() == defaultAmount(g.GenOper, preset)
+				} else {
+					redundant = g.GenAmount.AsInt16() == inherited(g.GenOper)
+				}
+			}
+			if !redundant {
+				out = append(out, g)
+			}
+		}
+		zones[i].Generators = out
+	}
+
+	if global && len(zones[0].Generators) == 0 && len(zones[0].Modulators) == 0 {
+		zones = zones[1:]
+	}
+	return zones
+}
+
+// sortGenerators puts a zone's generators in spec order and, within that,
+// the ones free to go anywhere by operator, so the same set of generators
+// always comes out the same way round.
+func sortGenerators(gens []Generator) {
+	sort.SliceStable(gens, func(i, j int) bool {
+		a, b := generatorRank(gens[i].GenOper), generatorRank(gens[j].GenOper)
+		if a != b {
+			return a < b
+		}
+		return gens[i].GenOper < gens[j].GenOper
+	})
+}
+
+// Canonicalize rewrites the hydra so that banks with the same content have
+// the same tables: presets sorted by bank and program, generators in spec
+// order and otherwise sorted by operator, without repeats or redundant
+// values, and names zero padded after
+// their terminator. Playback is unchanged.
+func (h *SoundFontHydra) Canonicalize() error {
+	l, err := h.Unpack()
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(l.Presets, func(i, j int) bool {
+		a, b := l.Presets[i].Header, l.Presets[j].Header
+		if a.Bank != b.Bank {
+			return a.Bank < b.Bank
+		}
+		return a.Preset < b.Preset
+	})
+
+	for i := range l.Presets {
+		p := &l.Presets[i]
+		p.Header.PresetName = fixedName(trimName(p.Header.PresetName))
+		p.Zones = removeRedundantGenerators(p.Zones, Gen_Instrument)
+		for _, z := range p.Zones {
+			sortGenerators(z.Generators)
+		}
+	}
+	for i := range l.Instruments {
+		inst := &l.Instruments[i]
+		inst.Name = fixedName(trimName(inst.Name))
+		inst.Zones = removeRedundantGenerators(inst.Zones, Gen_SampleID)
+		for _, z := range inst.Zones {
+			sortGenerators(z.Generators)
+		}
+	}
+	for i := range l.Samples {
+		l.Samples[i].SampleName = fixedName(trimName(l.Samples[i].SampleName))
+	}
+
+	packed, err := l.Pack()
+	if err != nil {
+		return err
+	}
+	*h = *packed
+	return nil
+}
@@ -0,0 +1,37 @@
+package sf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalizeGeneratorOrder(t *testing.T) {
+	withGens := func(gens ...Generator) *SoundFontHydra {
+		l, err := GenerateSineBank(1).Hydra.Unpack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := &l.Instruments[0].Zones[0]
+		z.Generators = append(gens, z.Generators...)
+		h, err := l.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	pan := Generator{GenOper: Gen_Pan, GenAmount: 100}
+	tune := Generator{GenOper: Gen_FineTune, GenAmount: 5}
+	cutoff := Generator{GenOper: Gen_InitialFilterFc, GenAmount: 9000}
+
+	a := withGens(pan, tune, cutoff)
+	b := withGens(cutoff, pan, tune)
+	if err := a.Canonicalize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Canonicalize(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("the same generators in another order canonicalize differently:\n%v\n%v", a.InstrumentGenerators, b.InstrumentGenerators)
+	}
+}
@@ -0,0 +1,234 @@
+package sf
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Catalog describes every bank in a folder in one JSON document, so that a
+// library can list and search a collection without parsing each bank.
+type Catalog struct {
+	Dir   string        `json:"dir"`
+	Banks []CatalogBank `json:"banks"`
+}
+
+// CatalogBank is a bank's entry in a Catalog.
+type CatalogBank struct {
+	// Path is relative to the catalog's directory.
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+
+	// Fingerprint is the SHA-256 of the whole file.
+	Fingerprint string `json:"fingerprint"`
+
+	Info    *SoundFontInfo  `json:"info,omitempty"`
+	Presets []CatalogPreset `json:"presets,omitempty"`
+
+	// Error is why the bank could not be read, the entry then only has its
+	// path, size, time and fingerprint.
+	Error string `json:"error,omitempty"`
+}
+
+// CatalogPreset is a preset's entry in a CatalogBank.
+type CatalogPreset struct {
+	Name    string `json:"name"`
+	Bank    uint16 `json:"bank"`
+	Program uint16 `json:"program"`
+	Family  string `json:"family,omitempty"`
+
+	// Fingerprint is the SHA-256 of what the preset plays: its zones with the
+	// instruments and samples they use, but not its name, bank or program.
+	// Equal presets in different banks have equal fingerprints.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// presetFingerprints returns the content fingerprint of every preset.
+func (sf *SoundFont) presetFingerprints() ([]string, error) {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return nil, err
+	}
+
+	sampleSigs := make([]string, len(l.Samples))
+	for i, s := range l.Samples {
+		sampleSigs[i] = sf.sampleContentSig(s)
+	}
+	instSigs := make([]string, len(l.Instruments))
+	for i, inst := range l.Instruments {
+		instSigs[i] = zonesContentSig(inst.Zones, Gen_SampleID, sampleSigs)
+	}
+
+	prints := make([]string, len(l.Presets))
+	for i, p := range l.Presets {
+		sum := sha256.Sum256([]byte(zonesContentSig(p.Zones, Gen_Instrument, instSigs)))
+		prints[i] = hex.EncodeToString(sum[:])
+	}
+	return prints, nil
+}
+
+// catalogBank reads the bank at path, hashing the file as it is parsed.
+func catalogBank(dir, rel string) (CatalogBank, error) {
+	b := CatalogBank{Path: rel}
+
+	f, err := os.Open(filepath.Join(dir, rel))
+	if err != nil {
+		return b, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return b, err
+	}
+	b.Size, b.ModTime = st.Size(), st.ModTime()
+
+	h := sha256.New()
+	r := io.TeeReader(f, h)
+	var sf *SoundFont
+	if MaxLoadSize > 0 && b.Size > MaxLoadSize {
+		err = fmt.Errorf("%d bytes is over the %d byte limit", b.Size, MaxLoadSize)
+	} else {
+		sf, err = ReadSoundFont(bufio.NewReader(r))
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return b, err
+	}
+	b.Fingerprint = hex.EncodeToString(h.Sum(nil))
+
+	var prints []string
+	if err == nil {
+		prints, err = sf.presetFingerprints()
+	}
+	if err != nil {
+		b.Error = err.Error()
+		return b, nil
+	}
+
+	b.Info = sf.Info
+	for i, fp := range prints {
+		hd := sf.Hydra.Headers[i]
+		b.Presets = append(b.Presets, CatalogPreset{
+			Name:        trimName(hd.PresetName),
+			Bank:        hd.Bank,
+			Program:     hd.Preset,
+			Family:      sf.ClassifyPreset(i).Family,
+			Fingerprint: fp,
+		})
+	}
+	return b, nil
+}
+
+// BuildCatalog catalogs the .sf2 files in dir. Banks that fail to parse are
+// listed with their Error set.
+func BuildCatalog(dir string) (*Catalog, error) {
+	c := &Catalog{Dir: dir}
+	if _, err := c.Update(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update brings the catalog in line with its directory, only reading banks
+// whose size or modification time changed. It returns the paths of the banks
+// added, changed or removed.
+func (c *Catalog) Update() ([]string, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	old := map[string]CatalogBank{}
+	for _, b := range c.Banks {
+		old[b.Path] = b
+	}
+
+	var banks []CatalogBank
+	var changed []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".sf2") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		prev, ok := old[e.Name()]
+		delete(old, e.Name())
+		if ok && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+			banks = append(banks, prev)
+			continue
+		}
+
+		b, err := catalogBank(c.Dir, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		banks = append(banks, b)
+		changed = append(changed, b.Path)
+	}
+	for path := range old {
+		changed = append(changed, path)
+	}
+
+	sort.Slice(banks, func(i, j int) bool { return banks[i].Path < banks[j].Path })
+	sort.Strings(changed)
+	c.Banks = banks
+	return changed, nil
+}
+
+// Apply updates the catalog for an event of a Watcher on its directory. The
+// bank is read again to fingerprint it.
+func (c *Catalog) Apply(ev WatchEvent) error {
+	rel, err := filepath.Rel(c.Dir, ev.Path)
+	if err != nil {
+		return err
+	}
+
+	i := sort.Search(len(c.Banks), func(i int) bool { return c.Banks[i].Path >= rel })
+	found := i < len(c.Banks) && c.Banks[i].Path == rel
+
+	if ev.Op == Watch_Remove {
+		if found {
+			c.Banks = append(c.Banks[:i], c.Banks[i+1:]...)
+		}
+		return nil
+	}
+
+	b, err := catalogBank(c.Dir, rel)
+	if err != nil {
+		return err
+	}
+	if found {
+		c.Banks[i] = b
+	} else {
+		c.Banks = append(c.Banks, CatalogBank{})
+		copy(c.Banks[i+1:], c.Banks[i:])
+		c.Banks[i] = b
+	}
+	return nil
+}
+
+// LoadCatalog reads a catalog written by Save.
+func LoadCatalog(r io.Reader) (*Catalog, error) {
+	c := &Catalog{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	return c, nil
+}
+
+// Save writes the catalog as JSON.
+func (c *Catalog) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
@@ -1,4 +1,4 @@
-package main
+package sf
 
 import (
 	"bytes"
@@ -31,15 +31,39 @@ func (ck *chunk) parse(r io.Reader) error {
 	}
 
 	// Read the chunk data.
-	ck.data = make([]byte, ck.size)
-	if _, err := io.ReadFull(r, ck.data); err != nil {
+	data, err := readChunkData(r, ck.size)
+	if err != nil {
 		return err
 	}
+	ck.data = data
 
 	// fmt.Println(string(ck.id[:]), ck.size, len(ck.data))
 	return nil
 }
 
+// chunkPrealloc caps the buffer allocated up front for a chunk's data.
+const chunkPrealloc = 16 << 20
+
+// readChunkData reads size bytes of chunk data. The buffer grows as the data
+// arrives instead of trusting size up front, so a stream whose header claims
+// more than it holds fails when it runs dry rather than allocating the claim.
+// On a short read the bytes read are returned with io.ErrUnexpectedEOF.
+func readChunkData(r io.Reader, size uint32) ([]byte, error) {
+	var buf bytes.Buffer
+	if size <= chunkPrealloc {
+		buf.Grow(int(size))
+	} else {
+		buf.Grow(chunkPrealloc)
+	}
+	if _, err := io.CopyN(&buf, r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
+
 // expect reads a chunk from the reader and checks that it's id matches the
 // expected id.
 func (ch *chunk) expect(r io.Reader, id [4]byte) error {
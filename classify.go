@@ -0,0 +1,91 @@
+package sf
+
+import "strings"
+
+// Category is a guess at what a preset sounds like, named after a General
+// MIDI family (see GMFamilyNames) or "Drums" for kits.
+type Category struct {
+	Family string
+
+	// Source is what the guess is based on: "bank", "name", "program" or
+	// "samples".
+	Source string
+
+	// Confidence is a rough 0 to 1 measure of how reliable the source is.
+	Confidence float64
+}
+
+// DrumsCategory is the family given to drum kits.
+const DrumsCategory = "Drums"
+
+// categoryKeywords map words found in preset names to families. A keyword
+// matches the start of a word. The list is ordered, earlier entries win, so
+// that "synth bass" is a bass and "string pad" a pad.
+var categoryKeywords = []struct {
+	word, family string
+}{
+	{"drum kit", DrumsCategory}, {"kit", DrumsCategory}, {"drums", DrumsCategory},
+	{"fx", "Synth Effects"}, {"sfx", "Sound Effects"}, {"noise", "Sound Effects"},
+	{"pad", "Synth Pad"}, {"lead", "Synth Lead"},
+	{"bassoon", "Reed"}, {"bass", "Bass"},
+	{"piano", "Piano"}, {"rhodes", "Piano"}, {"wurli", "Piano"}, {"e.p", "Piano"}, {"harpsi", "Piano"}, {"clav", "Piano"},
+	{"organ", "Organ"}, {"accordion", "Organ"}, {"harmonica", "Organ"},
+	{"guitar", "Guitar"}, {"gtr", "Guitar"},
+	{"violin", "Strings"}, {"viola", "Strings"}, {"cello", "Strings"}, {"harp", "Strings"}, {"timpani", "Strings"},
+	{"string", "Ensemble"}, {"choir", "Ensemble"}, {"voice", "Ensemble"}, {"aahs", "Ensemble"}, {"oohs", "Ensemble"},
+	{"trumpet", "Brass"}, {"trombone", "Brass"}, {"tuba", "Brass"}, {"horn", "Brass"}, {"brass", "Brass"},
+	{"sax", "Reed"}, {"oboe", "Reed"}, {"clarinet", "Reed"},
+	{"flute", "Pipe"}, {"piccolo", "Pipe"}, {"recorder", "Pipe"}, {"whistle", "Pipe"}, {"ocarina", "Pipe"},
+	{"celesta", "Chromatic Percussion"}, {"glock", "Chromatic Percussion"}, {"vibra", "Chromatic Percussion"},
+	{"marimba", "Chromatic Percussion"}, {"xylo", "Chromatic Percussion"}, {"bell", "Chromatic Percussion"},
+	{"sitar", "Ethnic"}, {"banjo", "Ethnic"}, {"koto", "Ethnic"}, {"shamisen", "Ethnic"}, {"kalimba", "Ethnic"}, {"pipe", "Ethnic"},
+	{"perc", "Percussive"}, {"tom", "Percussive"}, {"taiko", "Percussive"}, {"cymbal", "Percussive"}, {"block", "Percussive"},
+	{"synth", "Synth Lead"},
+}
+
+// ClassifyPreset guesses the category of the preset at index i in Headers.
+// Drum banks are recognized first, then keywords in the preset name, then the
+// General MIDI family of its program number in bank 0, and finally whether
+// its samples are short and unlooped like percussion. The zero Category means
+// no guess could be made.
+func (sf *SoundFont) ClassifyPreset(i int) Category {
+	h := sf.Hydra
+	if i < 0 || i+1 >= len(h.Headers) {
+		return Category{}
+	}
+	p := h.Headers[i]
+
+	if p.Bank == 128 {
+		return Category{DrumsCategory, "bank", 0.9}
+	}
+
+	name := " " + strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return ' '
+		}
+		return r
+	}, strings.ToLower(trimName(p.PresetName)))
+	for _, k := range categoryKeywords {
+		if strings.Contains(name, " "+k.word) {
+			return Category{k.family, "name", 0.8}
+		}
+	}
+
+	if p.Bank == 0 && p.Preset < 128 {
+		return Category{GMFamilyNames[p.Preset/8], "program", 0.5}
+	}
+
+	// percussion plays short, unlooped samples
+	regions, err := h.Regions(i, 60, 100)
+	if err != nil || len(regions) == 0 {
+		return Category{}
+	}
+	for _, r := range regions {
+		a := r.Addresses()
+		if r.SampleMode().loops() || r.Sample.SampleRate == 0 ||
+			float64(a.End-a.Start)/float64(r.Sample.SampleRate) > 0.5 {
+			return Category{}
+		}
+	}
+	return Category{"Percussive", "samples", 0.3}
+}
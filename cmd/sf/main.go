@@ -0,0 +1,36 @@
+//go:build !js
+
+// Command sf reads a SoundFont, test.sf2 or the file named by its first
+// argument, to check that it parses.
+package main
+
+import (
+	"os"
+
+	"github.com/Alextopher/sf"
+)
+
+func main() {
+	path := "test.sf2"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	// open the test file
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+
+	defer f.Close()
+
+	// read the file
+	_, err = sf.ReadSoundFont(f)
+	if err != nil {
+		panic(err)
+	}
+
+	// do something with the sound font
+	// ...
+	// fmt.Println(sf)
+}
@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+
+	"github.com/Alextopher/sf"
+)
+
+// main exposes a global sf object to JavaScript:
+//
+//	const bank = sf.load(uint8Array)  // {error: message} on a malformed file
+//	bank.presets()                   // [{name, bank, program}, ...]
+//
+// Errors are returned as values: a Go panic inside a callback would take the
+// whole runtime down with it.
+func main() {
+	js.Global().Set("sf", js.ValueOf(map[string]interface{}{
+		"load": js.FuncOf(jsLoad),
+	}))
+
+	// keep the callbacks alive
+	select {}
+}
+
+// jsError is the value a callback returns in place of its result on failure.
+func jsError(msg string) interface{} {
+	return js.ValueOf(map[string]interface{}{"error": msg})
+}
+
+// jsLoad parses the SoundFont held in a Uint8Array.
+func jsLoad(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return jsError("sf.load expects a Uint8Array")
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+
+	bank, err := sf.ReadSoundFont(bytes.NewReader(data))
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	return js.ValueOf(map[string]interface{}{
+		"presets": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return jsPresets(bank)
+		}),
+	})
+}
+
+// jsPresets lists a SoundFont's presets, without the terminal record.
+func jsPresets(bank *sf.SoundFont) interface{} {
+	headers := bank.Hydra.Headers
+	if len(headers) > 0 {
+		headers = headers[:len(headers)-1]
+	}
+
+	presets := make([]interface{}, len(headers))
+	for i, p := range headers {
+		presets[i] = map[string]interface{}{
+			"name":    p.Name(),
+			"bank":    int(p.Bank),
+			"program": int(p.Preset),
+		}
+	}
+	return presets
+}
@@ -0,0 +1,156 @@
+// Command sfd serves the SoundFonts in a directory over HTTP, for web apps
+// that inspect banks server-side:
+//
+//	GET  /banks                 names of the .sf2 files in the directory
+//	GET  /banks/{name}/presets  [{name, bank, program}, ...]
+//	POST /validate              the spec problems of the SoundFont in the body
+//
+// Banks are loaded on first use and kept in a memory-bounded Pool. Errors are
+// returned as {"error": message} with a 4xx or 5xx status.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Alextopher/sf"
+)
+
+type server struct {
+	dir  string
+	pool *sf.Pool
+	// maxUpload is the largest body /validate reads.
+	maxUpload int64
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dir := flag.String("dir", ".", "directory holding the banks")
+	budget := flag.Int64("budget", 1<<30, "bytes of banks to keep in memory")
+	maxUpload := flag.Int64("max-upload", 256<<20, "largest SoundFont /validate accepts, in bytes")
+	flag.Parse()
+
+	s := &server{dir: *dir, maxUpload: *maxUpload}
+	s.pool = sf.NewPool(*budget, func(name string) (*sf.SoundFont, error) {
+		return sf.LoadFile(filepath.Join(s.dir, name))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/banks", s.banks)
+	mux.HandleFunc("/banks/", s.presets)
+	mux.HandleFunc("/validate", s.validate)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// reply writes v as JSON.
+func reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+func replyError(w http.ResponseWriter, status int, msg string) {
+	reply(w, status, map[string]string{"error": msg})
+}
+
+// banks lists the SoundFonts in the directory.
+func (s *server) banks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		replyError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		replyError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".sf2") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	reply(w, http.StatusOK, names)
+}
+
+type preset struct {
+	Name    string `json:"name"`
+	Bank    uint16 `json:"bank"`
+	Program uint16 `json:"program"`
+}
+
+// presets lists the presets of one bank.
+func (s *server) presets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		replyError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, "/banks/")
+	name := strings.TrimSuffix(rest, "/presets")
+	// a name must be a file directly in the directory
+	if name == rest || name == "" || name != filepath.Base(name) || name == ".." {
+		replyError(w, http.StatusNotFound, "no such endpoint")
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, name)); err != nil {
+		replyError(w, http.StatusNotFound, "no bank "+name)
+		return
+	}
+
+	bank, err := s.pool.Get(name)
+	if err != nil {
+		replyError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	headers := bank.Hydra.Headers
+	if len(headers) > 0 {
+		headers = headers[:len(headers)-1]
+	}
+	list := make([]preset, len(headers))
+	for i, p := range headers {
+		list[i] = preset{Name: p.Name(), Bank: p.Bank, Program: p.Preset}
+	}
+	reply(w, http.StatusOK, list)
+}
+
+type problem struct {
+	Rule    string `json:"rule"`
+	Where   string `json:"where"`
+	Message string `json:"message"`
+}
+
+// validate parses the uploaded SoundFont and reports its spec problems. A
+// file that does not parse at all is a 422.
+func (s *server) validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		replyError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxUpload))
+	if err != nil {
+		replyError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	bank, err := sf.ReadSoundFont(bytes.NewReader(data))
+	if err != nil {
+		replyError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	problems := []problem{}
+	for _, p := range bank.Hydra.Validate() {
+		problems = append(problems, problem{Rule: p.Rule, Where: p.Where, Message: p.Message})
+	}
+	reply(w, http.StatusOK, map[string]interface{}{"problems": problems})
+}
@@ -0,0 +1,136 @@
+package sf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// An error corpus is a directory of small, deliberately broken banks used as
+// a regression bed for the parser and validator. Each fixture NAME.sf2 sits
+// next to NAME.expect.json describing what reading it must produce:
+//
+//	{"parseError": "expected sdta"}
+//	{"rules": ["bad-range", "bad-generator-order"]}
+//
+// parseError is a substring of the error ReadSoundFont must return. Without
+// it the file must parse, and rules lists the distinct Problem.Rule values
+// Validate must report, in any order. An empty rules list means the file must
+// validate cleanly.
+
+// CorpusCase is one fixture of an error corpus.
+type CorpusCase struct {
+	Name string
+	Path string
+
+	ParseError string   `json:"parseError"`
+	Rules      []string `json:"rules"`
+}
+
+// CorpusResult is the outcome of running a CorpusCase.
+type CorpusResult struct {
+	Case CorpusCase
+
+	// ParseError is the error returned by ReadSoundFont, if any.
+	ParseError error
+	// Rules are the distinct rules Validate reported, sorted.
+	Rules []string
+
+	// Failure explains why the case failed, it is empty when it passed.
+	Failure string
+}
+
+// Passed reports whether the fixture behaved as expected.
+func (r CorpusResult) Passed() bool {
+	return r.Failure == ""
+}
+
+// LoadCorpus lists the fixtures in dir, sorted by name. Every .sf2 file must
+// have its .expect.json.
+func LoadCorpus(dir string) ([]CorpusCase, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sf2"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	cases := make([]CorpusCase, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".sf2")
+		data, err := os.ReadFile(filepath.Join(dir, name+".expect.json"))
+		if err != nil {
+			return nil, fmt.Errorf("corpus: fixture %s: %w", name, err)
+		}
+		c := CorpusCase{Name: name, Path: path}
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("corpus: fixture %s: %w", name, err)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Run reads and validates the fixture and compares the outcome with the
+// expectation.
+func (c CorpusCase) Run() CorpusResult {
+	res := CorpusResult{Case: c}
+
+	f, err := os.Open(c.Path)
+	if err != nil {
+		res.Failure = err.Error()
+		return res
+	}
+	defer f.Close()
+
+	sf, err := ReadSoundFont(f)
+	res.ParseError = err
+	switch {
+	case err != nil && c.ParseError == "":
+		res.Failure = fmt.Sprintf("unexpected parse error: %v", err)
+		return res
+	case err != nil && !strings.Contains(err.Error(), c.ParseError):
+		res.Failure = fmt.Sprintf("parse error %q does not contain %q", err, c.ParseError)
+		return res
+	case err != nil:
+		return res
+	case c.ParseError != "":
+		res.Failure = fmt.Sprintf("parsed, expected an error containing %q", c.ParseError)
+		return res
+	}
+
+	seen := map[string]bool{}
+	for _, p := range sf.Hydra.Validate() {
+		if !seen[p.Rule] {
+			seen[p.Rule] = true
+			res.Rules = append(res.Rules, p.Rule)
+		}
+	}
+	sort.Strings(res.Rules)
+
+	want := append([]string(nil), c.Rules...)
+	sort.Strings(want)
+	if len(want) == 0 && len(res.Rules) == 0 {
+		return res
+	}
+	if !reflect.DeepEqual(want, res.Rules) {
+		res.Failure = fmt.Sprintf("validator reported %v, expected %v", res.Rules, want)
+	}
+	return res
+}
+
+// RunCorpus loads and runs every fixture in dir.
+func RunCorpus(dir string) ([]CorpusResult, error) {
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CorpusResult, len(cases))
+	for i, c := range cases {
+		results[i] = c.Run()
+	}
+	return results, nil
+}
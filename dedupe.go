@@ -0,0 +1,91 @@
+package sf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sampleContentSig identifies a sample by its data and playback parameters,
+// ignoring its name and where it sits in the sample data field.
+func (sf *SoundFont) sampleContentSig(s SampleHeader) string {
+	if s.isROM() {
+		return fmt.Sprintf("rom %d-%d loop %d-%d rate %d key %d corr %d type %d",
+			s.Start, s.End, s.Startloop, s.Endloop, s.SampleRate, s.OriginalPitch, s.PitchCorrection, s.SampleType)
+	}
+	return fmt.Sprintf("%s loop %d-%d rate %d key %d corr %d type %d",
+		sampleHash(sf.SampleData(s)), int64(s.Startloop)-int64(s.Start), int64(s.Endloop)-int64(s.Start),
+		s.SampleRate, s.OriginalPitch, s.PitchCorrection, s.SampleType)
+}
+
+// zonesContentSig describes zones with the terminal generator's target
+// replaced by its entry in sigs, so that zones can be compared by what they
+// play rather than by index.
+func zonesContentSig(zones []Zone, terminal SFGenerator, sigs []string) string {
+	var b strings.Builder
+	for _, z := range zones {
+		for _, g := range z.Generators {
+			if g.GenOper == terminal && int(uint16(g.GenAmount)) < len(sigs) {
+				fmt.Fprintf(&b, "%s=%q;", terminal.String(), sigs[uint16(g.GenAmount)])
+			} else {
+				fmt.Fprintf(&b, "%d=%d;", g.GenOper, g.GenAmount)
+			}
+		}
+		for _, m := range z.Modulators {
+			fmt.Fprintf(&b, "mod%v;", m)
+		}
+		b.WriteString("|")
+	}
+	return b.String()
+}
+
+// DedupeInstruments removes instruments that are identical to an earlier one
+// apart from their name, pointing the preset zones that used them at the one
+// kept. Two instruments are identical when their zones have the same
+// generators and modulators in the same order and play samples with the same
+// data and parameters. It returns the number of instruments removed.
+// Samples are left in place even if nothing uses them anymore.
+func (sf *SoundFont) DedupeInstruments() (int, error) {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return 0, err
+	}
+
+	sampleSigs := make([]string, len(l.Samples))
+	for i, s := range l.Samples {
+		sampleSigs[i] = sf.sampleContentSig(s)
+	}
+
+	seen := map[string]int{}
+	remap := make([]int, len(l.Instruments))
+	var kept []InstrumentData
+	for i, inst := range l.Instruments {
+		sig := zonesContentSig(inst.Zones, Gen_SampleID, sampleSigs)
+		if j, ok := seen[sig]; ok {
+			remap[i] = j
+			continue
+		}
+		seen[sig] = len(kept)
+		remap[i] = len(kept)
+		kept = append(kept, inst)
+	}
+
+	removed := len(l.Instruments) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	for _, p := range l.Presets {
+		for _, z := range p.Zones {
+			for k, g := range z.Generators {
+				if g.GenOper == Gen_Instrument && int(uint16(g.GenAmount)) < len(remap) {
+					z.Generators[k].GenAmount = GenAmount(remap[uint16(g.GenAmount)])
+				}
+			}
+		}
+	}
+	l.Instruments = kept
+	if sf.Hydra, err = l.Pack(); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
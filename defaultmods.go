@@ -0,0 +1,115 @@
+package sf
+
+// DefaultModulators are the modulators every instrument zone starts with, as
+// listed in section 8.4 of the specification.
+var DefaultModulators = []Modulator{
+	// MIDI note-on velocity to initial attenuation
+	{ModSrcOper: 0x0502, ModDestOper: Gen_InitialAttenuation, ModAmount: 960},
+	// MIDI note-on velocity to filter cutoff
+	{ModSrcOper: 0x0102, ModDestOper: Gen_InitialFilterFc, ModAmount: -2400},
+	// MIDI channel pressure to vibrato LFO pitch depth
+	{ModSrcOper: 0x000D, ModDestOper: Gen_VibLfoToPitch, ModAmount: 50},
+	// MIDI CC 1 (modulation wheel) to vibrato LFO pitch depth
+	{ModSrcOper: 0x0081, ModDestOper: Gen_VibLfoToPitch, ModAmount: 50},
+	// MIDI CC 7 (volume) to initial attenuation
+	{ModSrcOper: 0x0587, ModDestOper: Gen_InitialAttenuation, ModAmount: 960},
+	// MIDI CC 10 (pan) to pan position
+	{ModSrcOper: 0x028A, ModDestOper: Gen_Pan, ModAmount: 1000},
+	// MIDI CC 11 (expression) to initial attenuation
+	{ModSrcOper: 0x058B, ModDestOper: Gen_InitialAttenuation, ModAmount: 960},
+	// MIDI CC 91 to reverb send
+	{ModSrcOper: 0x00DB, ModDestOper: Gen_ReverbEffectsSend, ModAmount: 200},
+	// MIDI CC 93 to chorus send
+	{ModSrcOper: 0x00DD, ModDestOper: Gen_ChorusEffectsSend, ModAmount: 200},
+	// MIDI pitch wheel to initial pitch, scaled by pitch wheel sensitivity
+	{ModSrcOper: 0x020E, ModDestOper: Gen_InitialPitch, ModAmount: 12700, ModAmtSrcOper: 0x0010},
+}
+
+// identical reports whether two modulators share their source, destination,
+// amount source and transform, the spec's definition of identical modulators.
+// The amount is not compared.
+func (m Modulator) identical(o Modulator) bool {
+	return m.ModSrcOper == o.ModSrcOper &&
+		m.ModDestOper == o.ModDestOper &&
+		m.ModAmtSrcOper == o.ModAmtSrcOper &&
+		m.ModTransOper == o.ModTransOper
+}
+
+// supersede returns base with every modulator of mods applied on top of it.
+// A modulator identical to one of base replaces it, others are appended. A
+// modulator identical to an earlier one of mods is ignored, as the spec
+// requires of identical modulators in the same zone.
+func supersede(base []Modulator, mods []Modulator) []Modulator {
+	out := append([]Modulator(nil), base...)
+	// fromMods[i] is set once out[i] came from mods
+	fromMods := make([]bool, len(out))
+next:
+	for _, m := range mods {
+		for i := range out {
+			if out[i].identical(m) {
+				if !fromMods[i] {
+					out[i] = m
+					fromMods[i] = true
+				}
+				continue next
+			}
+		}
+		out = append(out, m)
+		fromMods = append(fromMods, true)
+	}
+	return out
+}
+
+// EffectiveModulators returns the modulators in effect for a voice started
+// from the instrument zone inst, reached through the preset zone preset.
+// preset may be nil to inspect an instrument on its own.
+//
+// At each level the local zone supersedes identical modulators of its global
+// zone, and the instrument level supersedes identical default modulators.
+// Preset modulators are then added to the instrument level: an identical
+// modulator has its amount summed rather than being applied twice.
+func EffectiveModulators(inst, preset *Zone) []Modulator {
+	mods := DefaultModulators
+	if inst != nil {
+		if inst.Global != nil {
+			mods = supersede(mods, inst.Global.Modulators)
+		}
+		mods = supersede(mods, inst.Modulators)
+	} else {
+		mods = append([]Modulator(nil), mods...)
+	}
+
+	if preset == nil {
+		return mods
+	}
+
+	var presetMods []Modulator
+	if preset.Global != nil {
+		presetMods = supersede(presetMods, preset.Global.Modulators)
+	}
+	presetMods = supersede(presetMods, preset.Modulators)
+
+next:
+	for _, m := range presetMods {
+		for i := range mods {
+			if mods[i].identical(m) {
+				mods[i].ModAmount = clampInt16(int(mods[i].ModAmount) + int(m.ModAmount))
+				continue next
+			}
+		}
+		mods = append(mods, m)
+	}
+
+	return mods
+}
+
+// clampInt16 saturates v to the range of an int16.
+func clampInt16(v int) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
@@ -0,0 +1,62 @@
+package sf
+
+import "testing"
+
+// TestDefaultModulators checks DefaultModulators against the table of
+// section 8.4 of the specification, as raw field values.
+func TestDefaultModulators(t *testing.T) {
+	spec := []struct {
+		name                  string
+		src, dest, amt, trans uint16
+		amount                int16
+	}{
+		{"8.4.1 velocity to attenuation", 0x0502, 48, 0, 0, 960},
+		{"8.4.2 velocity to filter cutoff", 0x0102, 8, 0, 0, -2400},
+		{"8.4.3 channel pressure to vibrato depth", 0x000D, 6, 0, 0, 50},
+		{"8.4.4 CC1 to vibrato depth", 0x0081, 6, 0, 0, 50},
+		{"8.4.5 CC7 to attenuation", 0x0587, 48, 0, 0, 960},
+		{"8.4.6 CC10 to pan", 0x028A, 17, 0, 0, 1000},
+		{"8.4.7 CC11 to attenuation", 0x058B, 48, 0, 0, 960},
+		{"8.4.8 CC91 to reverb send", 0x00DB, 16, 0, 0, 200},
+		{"8.4.9 CC93 to chorus send", 0x00DD, 15, 0, 0, 200},
+		{"8.4.10 pitch wheel to initial pitch", 0x020E, 59, 0x0010, 0, 12700},
+	}
+	if len(DefaultModulators) != len(spec) {
+		t.Fatalf("%d default modulators, the spec lists %d", len(DefaultModulators), len(spec))
+	}
+	for i, want := range spec {
+		m := DefaultModulators[i]
+		if uint16(m.ModSrcOper) != want.src || uint16(m.ModDestOper) != want.dest ||
+			uint16(m.ModAmtSrcOper) != want.amt || uint16(m.ModTransOper) != want.trans || m.ModAmount != want.amount {
+			t.Errorf("%s: got %+v", want.name, m)
+		}
+	}
+
+	// the volume and expression sources decode to their controllers
+	for i, cc := range map[int]uint8{4: 7, 6: 11} {
+		if m := DefaultModulators[i].ModSrcOper; !m.IsMIDICC() || m.Controller() != cc {
+			t.Errorf("modulator %d reads %v, want CC%d", i, m, cc)
+		}
+	}
+}
+
+func TestEffectiveModulatorsDuplicates(t *testing.T) {
+	velocity := DefaultModulators[0]
+	first, second := velocity, velocity
+	first.ModAmount, second.ModAmount = 480, 100
+
+	inst := &Zone{Modulators: []Modulator{first, second}}
+	mods := EffectiveModulators(inst, nil)
+	if len(mods) != len(DefaultModulators) {
+		t.Fatalf("got %d modulators, want %d", len(mods), len(DefaultModulators))
+	}
+	if mods[0].ModAmount != 480 {
+		t.Errorf("velocity to attenuation has amount %d, want the first duplicate's 480", mods[0].ModAmount)
+	}
+
+	// a local zone still supersedes its global zone
+	inst = &Zone{Global: &Zone{Modulators: []Modulator{first}}, Modulators: []Modulator{second}}
+	if mods := EffectiveModulators(inst, nil); mods[0].ModAmount != 100 {
+		t.Errorf("local modulator amount %d did not supersede the global zone's", mods[0].ModAmount)
+	}
+}
@@ -0,0 +1,485 @@
+package sf
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The disassembly is a line based text form of a bank, meant to be kept under
+// version control and reviewed like code. Sample audio is not included, each
+// sample refers to its data points by a SHA-256 hash:
+//
+//	soundfont 2.1
+//	info name "Sine Bank"
+//	sample 0 "Sine" sha256=1f2e... length=2100 loop=1000-2000 rate=44000 key=69 correction=0 link=0 type=1
+//	instrument 0 "Sine"
+//	  zone
+//	    gen sampleModes 1
+//	    gen sampleID 0
+//	preset 0 "Sine" bank=0 program=0 library=0 genre=0 morphology=0
+//	  zone
+//	    gen keyRange 0-127
+//	    mod 0x0502 initialAttenuation 960 0x0000 0
+//	    gen instrument 0
+//
+// Generators and modulators of a zone are listed in the order they are stored.
+// Lines starting with # are comments.
+
+// sampleHash returns the hash a disassembly uses to refer to a sample's data.
+func sampleHash(higher []int16, lower []int8) string {
+	h := sha256.New()
+	buf := make([]byte, 2*len(higher))
+	for i, v := range higher {
+		buf[2*i], buf[2*i+1] = byte(v), byte(uint16(v)>>8)
+	}
+	h.Write(buf)
+	if lower != nil {
+		buf = buf[:len(lower)]
+		for i, v := range lower {
+			buf[i] = byte(v)
+		}
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// infoFields lists the INFO strings in the order they are disassembled.
+func infoFields(info *SoundFontInfo) []struct {
+	key   string
+	value *string
+} {
+	return []struct {
+		key   string
+		value *string
+	}{
+		{"engine", &info.Engine},
+		{"name", &info.Name},
+		{"rom", &info.ROM},
+		{"date", &info.CreationDate},
+		{"engineers", &info.Engineers},
+		{"product", &info.Product},
+		{"copyright", &info.Copyright},
+		{"comments", &info.Comments},
+		{"software", &info.Software},
+	}
+}
+
+func disassembleZones(w *bufio.Writer, zones []Zone) {
+	for _, z := range zones {
+		fmt.Fprintln(w, "  zone")
+		for _, g := range z.Generators {
+			fmt.Fprintf(w, "    gen %s %s\n", g.GenOper.String(), formatAmount(g))
+		}
+		for _, m := range z.Modulators {
+			fmt.Fprintf(w, "    mod 0x%04x %s %d 0x%04x %d\n",
+				uint16(m.ModSrcOper), modulatorDest(m.ModDestOper), m.ModAmount, uint16(m.ModAmtSrcOper), uint16(m.ModTransOper))
+		}
+	}
+}
+
+// formatAmount writes ranges as lo-hi and every other amount as a number.
+func formatAmount(g Generator) string {
+	if g.GenOper == Gen_KeyRange || g.GenOper == Gen_VelRange {
+		lo, hi := g.GenAmount.AsRange()
+		return fmt.Sprintf("%d-%d", lo, hi)
+	}
+	return strconv.Itoa(int(g.GenAmount))
+}
+
+// Disassemble writes the bank's text form to w.
+func (sf *SoundFont) Disassemble(w io.Writer) error {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+
+	info := sf.Info
+	if info == nil {
+		info = &SoundFontInfo{}
+	}
+	fmt.Fprintf(bw, "soundfont %d.%d\n", info.SfVersion.Major, info.SfVersion.Minor)
+	if info.ROMVer.Major != 0 || info.ROMVer.Minor != 0 {
+		fmt.Fprintf(bw, "info rom-version %d.%d\n", info.ROMVer.Major, info.ROMVer.Minor)
+	}
+	for _, f := range infoFields(info) {
+		if *f.value != "" {
+			fmt.Fprintf(bw, "info %s %s\n", f.key, strconv.Quote(*f.value))
+		}
+	}
+
+	for i, s := range l.Samples {
+		fmt.Fprintf(bw, "sample %d %s ", i, strconv.Quote(trimName(s.SampleName)))
+		if s.isROM() {
+			fmt.Fprintf(bw, "start=%d end=%d loop=%d-%d", s.Start, s.End, s.Startloop, s.Endloop)
+		} else {
+			higher, lower := sf.SampleData(s)
+			loopStart, loopEnd := s.Startloop-s.Start, s.Endloop-s.Start
+			if s.Startloop < s.Start {
+				loopStart = 0
+			}
+			if s.Endloop < s.Start {
+				loopEnd = 0
+			}
+			fmt.Fprintf(bw, "sha256=%s length=%d loop=%d-%d", sampleHash(higher, lower), len(higher), loopStart, loopEnd)
+		}
+		fmt.Fprintf(bw, " rate=%d key=%d correction=%d link=%d type=%d\n",
+			s.SampleRate, s.OriginalPitch, s.PitchCorrection, s.SampleLink, uint16(s.SampleType))
+	}
+
+	for i, inst := range l.Instruments {
+		fmt.Fprintf(bw, "instrument %d %s\n", i, strconv.Quote(trimName(inst.Name)))
+		disassembleZones(bw, inst.Zones)
+	}
+
+	for i, p := range l.Presets {
+		h := p.Header
+		fmt.Fprintf(bw, "preset %d %s bank=%d program=%d library=%d genre=%d morphology=%d\n",
+			i, strconv.Quote(trimName(h.PresetName)), h.Bank, h.Preset, h.Library, h.Genre, h.Morphology)
+		disassembleZones(bw, p.Zones)
+	}
+
+	return bw.Flush()
+}
+
+// tokenize splits a line on spaces, keeping Go quoted strings whole.
+func tokenize(line string) ([]string, error) {
+	var tokens []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return tokens, nil
+		}
+		if line[0] == '"' {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, err
+			}
+			s, _ := strconv.Unquote(q)
+			tokens = append(tokens, s)
+			line = line[len(q):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		tokens = append(tokens, line[:end])
+		line = line[end:]
+	}
+}
+
+// parseGenerator parses a generator name, or genN for operators outside the
+// specification.
+func parseGenerator(name string) (SFGenerator, error) {
+	for op, n := range generatorNames {
+		if n == name {
+			return SFGenerator(op), nil
+		}
+	}
+	if strings.HasPrefix(name, "gen") {
+		if v, err := strconv.ParseUint(name[3:], 10, 16); err == nil {
+			return SFGenerator(v), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown generator %q", name)
+}
+
+// parsePair parses "a-b" as two unsigned numbers of the given bit size.
+func parsePair(s string, bits int) (uint64, uint64, error) {
+	i := strings.IndexByte(s, '-')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("expected a range, got %q", s)
+	}
+	a, err := strconv.ParseUint(s[:i], 10, bits)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.ParseUint(s[i+1:], 10, bits)
+	return a, b, err
+}
+
+// attrs parses key=value tokens.
+func attrs(tokens []string) (map[string]string, error) {
+	m := make(map[string]string, len(tokens))
+	for _, t := range tokens {
+		i := strings.IndexByte(t, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("expected key=value, got %q", t)
+		}
+		m[t[:i]] = t[i+1:]
+	}
+	return m, nil
+}
+
+// numbers parses the named attributes as unsigned numbers of the given bit
+// size, storing them through the pointers.
+func numbers(a map[string]string, bits int, dst map[string]*uint64) error {
+	for k, p := range dst {
+		s, ok := a[k]
+		if !ok {
+			return fmt.Errorf("missing %s=", k)
+		}
+		v, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		*p = v
+	}
+	return nil
+}
+
+// Assemble parses a disassembly. Sample data is looked up by hash among the
+// samples of audio, normally the bank the disassembly was made from.
+func Assemble(r io.Reader, audio *SoundFont) (*SoundFont, error) {
+	data := map[string]SampleHeader{}
+	if audio != nil && audio.Hydra != nil {
+		for _, s := range audio.Hydra.Samples {
+			if !s.isROM() {
+				data[sampleHash(audio.SampleData(s))] = s
+			}
+		}
+	}
+
+	info := &SoundFontInfo{}
+	l := &Layout{}
+	var pool samplePool
+	var zones *[]Zone
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t, err := tokenize(line)
+		if err == nil {
+			err = assembleLine(t, info, l, &pool, data, audio, &zones)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	hydra, err := l.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return &SoundFont{Info: info, Samples: pool.samples(), Hydra: hydra}, nil
+}
+
+func assembleLine(t []string, info *SoundFontInfo, l *Layout, pool *samplePool, data map[string]SampleHeader, audio *SoundFont, zones **[]Zone) error {
+	switch t[0] {
+	case "soundfont", "info":
+		if t[0] == "soundfont" {
+			t = []string{"info", "version", t[len(t)-1]}
+		}
+		if len(t) != 3 {
+			return fmt.Errorf("expected info <key> <value>")
+		}
+		switch t[1] {
+		case "version", "rom-version":
+			parts := strings.SplitN(t[2], ".", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid version %q", t[2])
+			}
+			major, err1 := strconv.ParseUint(parts[0], 10, 16)
+			minor, err2 := strconv.ParseUint(parts[1], 10, 16)
+			if err1 != nil || err2 != nil {
+				return fmt.Errorf("invalid version %q", t[2])
+			}
+			if t[1] == "version" {
+				info.SfVersion.Major, info.SfVersion.Minor = uint16(major), uint16(minor)
+			} else {
+				info.ROMVer.Major, info.ROMVer.Minor = uint16(major), uint16(minor)
+			}
+			return nil
+		}
+		for _, f := range infoFields(info) {
+			if f.key == t[1] {
+				*f.value = t[2]
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown info field %q", t[1])
+
+	case "sample":
+		if len(t) < 3 {
+			return fmt.Errorf("expected sample <index> <name> ...")
+		}
+		if t[1] != strconv.Itoa(len(l.Samples)) {
+			return fmt.Errorf("expected sample %d, got %s", len(l.Samples), t[1])
+		}
+		a, err := attrs(t[3:])
+		if err != nil {
+			return err
+		}
+		var rate, key, link, typ uint64
+		if err := numbers(a, 32, map[string]*uint64{"rate": &rate}); err != nil {
+			return err
+		}
+		if err := numbers(a, 16, map[string]*uint64{"key": &key, "link": &link, "type": &typ}); err != nil {
+			return err
+		}
+		correction, err := strconv.ParseInt(a["correction"], 10, 8)
+		if err != nil {
+			return fmt.Errorf("correction: %w", err)
+		}
+		loopStart, loopEnd, err := parsePair(a["loop"], 32)
+		if err != nil {
+			return fmt.Errorf("loop: %w", err)
+		}
+		s := SampleHeader{
+			SampleName:      fixedName(t[2]),
+			Startloop:       uint32(loopStart),
+			Endloop:         uint32(loopEnd),
+			SampleRate:      uint32(rate),
+			OriginalPitch:   uint8(key),
+			PitchCorrection: int8(correction),
+			SampleLink:      uint16(link),
+			SampleType:      SfSampleType(typ),
+		}
+
+		if s.isROM() {
+			var start, end uint64
+			if err := numbers(a, 32, map[string]*uint64{"start": &start, "end": &end}); err != nil {
+				return err
+			}
+			s.Start, s.End = uint32(start), uint32(end)
+			l.Samples = append(l.Samples, s)
+			return nil
+		}
+
+		src, ok := data[a["sha256"]]
+		if !ok {
+			return fmt.Errorf("sample %q: no audio with hash %s", t[2], a["sha256"])
+		}
+		higher, lower := audio.SampleData(src)
+		l.Samples = append(l.Samples, pool.add(s, higher, lower))
+		return nil
+
+	case "instrument":
+		if len(t) != 3 {
+			return fmt.Errorf("expected instrument <index> <name>")
+		}
+		if t[1] != strconv.Itoa(len(l.Instruments)) {
+			return fmt.Errorf("expected instrument %d, got %s", len(l.Instruments), t[1])
+		}
+		l.Instruments = append(l.Instruments, InstrumentData{Name: fixedName(t[2])})
+		*zones = &l.Instruments[len(l.Instruments)-1].Zones
+		return nil
+
+	case "preset":
+		if len(t) < 3 {
+			return fmt.Errorf("expected preset <index> <name> ...")
+		}
+		if t[1] != strconv.Itoa(len(l.Presets)) {
+			return fmt.Errorf("expected preset %d, got %s", len(l.Presets), t[1])
+		}
+		a, err := attrs(t[3:])
+		if err != nil {
+			return err
+		}
+		var bank, program, library, genre, morphology uint64
+		if err := numbers(a, 16, map[string]*uint64{"bank": &bank, "program": &program}); err != nil {
+			return err
+		}
+		if err := numbers(a, 32, map[string]*uint64{"library": &library, "genre": &genre, "morphology": &morphology}); err != nil {
+			return err
+		}
+		l.Presets = append(l.Presets, PresetData{Header: PresetHeader{
+			PresetName: fixedName(t[2]),
+			Preset:     uint16(program),
+			Bank:       uint16(bank),
+			Library:    uint32(library),
+			Genre:      uint32(genre),
+			Morphology: uint32(morphology),
+		}})
+		*zones = &l.Presets[len(l.Presets)-1].Zones
+		return nil
+
+	case "zone":
+		if *zones == nil {
+			return fmt.Errorf("zone outside of an instrument or preset")
+		}
+		**zones = append(**zones, Zone{})
+		return nil
+
+	case "gen", "mod":
+		if *zones == nil || len(**zones) == 0 {
+			return fmt.Errorf("%s outside of a zone", t[0])
+		}
+		z := &(**zones)[len(**zones)-1]
+		if t[0] == "gen" {
+			if len(t) != 3 {
+				return fmt.Errorf("expected gen <operator> <amount>")
+			}
+			op, err := parseGenerator(t[1])
+			if err != nil {
+				return err
+			}
+			g := Generator{GenOper: op}
+			if op == Gen_KeyRange || op == Gen_VelRange {
+				lo, hi, err := parsePair(t[2], 8)
+				if err != nil {
+					return err
+				}
+				g.GenAmount = RangeAmount(uint8(lo), uint8(hi))
+			} else {
+				v, err := strconv.ParseInt(t[2], 10, 16)
+				if err != nil {
+					return err
+				}
+				g.GenAmount = GenAmount(v)
+			}
+			z.Generators = append(z.Generators, g)
+			return nil
+		}
+
+		if len(t) != 6 {
+			return fmt.Errorf("expected mod <source> <destination> <amount> <amount source> <transform>")
+		}
+		src, err1 := strconv.ParseUint(t[1], 0, 16)
+		amount, err2 := strconv.ParseInt(t[3], 10, 16)
+		amtSrc, err3 := strconv.ParseUint(t[4], 0, 16)
+		trans, err4 := strconv.ParseUint(t[5], 0, 16)
+		for _, err := range []error{err1, err2, err3, err4} {
+			if err != nil {
+				return err
+			}
+		}
+		var dest SFGenerator
+		if strings.HasPrefix(t[2], "mod#") {
+			v, err := strconv.ParseUint(t[2][4:], 10, 15)
+			if err != nil {
+				return err
+			}
+			dest = modDestLink | SFGenerator(v)
+		} else {
+			op, err := parseGenerator(t[2])
+			if err != nil {
+				return err
+			}
+			dest = op
+		}
+		z.Modulators = append(z.Modulators, Modulator{
+			ModSrcOper:    SFModulator(src),
+			ModDestOper:   dest,
+			ModAmount:     int16(amount),
+			ModAmtSrcOper: SFModulator(amtSrc),
+			ModTransOper:  SFTransform(trans),
+		})
+		return nil
+	}
+
+	return fmt.Errorf("unknown statement %q", t[0])
+}
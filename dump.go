@@ -0,0 +1,67 @@
+package sf
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// generatorUnit returns a generator's amount converted to the unit a person
+// would think in, or "" when the raw amount is already that.
+func generatorUnit(op SFGenerator, amount int16) string {
+	v := float64(amount)
+	switch op {
+	case Gen_KeyRange, Gen_VelRange:
+		lo, hi := rangeBytes(amount)
+		return fmt.Sprintf("%d-%d", lo, hi)
+	case Gen_DelayModLFO, Gen_DelayVibLFO, Gen_DelayModEnv, Gen_AttackModEnv,
+		Gen_HoldModEnv, Gen_DecayModEnv, Gen_ReleaseModEnv, Gen_DelayVolEnv,
+		Gen_AttackVolEnv, Gen_HoldVolEnv, Gen_DecayVolEnv, Gen_ReleaseVolEnv:
+		return fmt.Sprintf("%.3g s", timecentsToSeconds(int(amount)))
+	case Gen_InitialFilterFc, Gen_FreqModLFO, Gen_FreqVibLFO:
+		return fmt.Sprintf("%.4g Hz", absoluteCentsToHz(int(amount)))
+	case Gen_InitialFilterQ, Gen_ModLfoToVolume, Gen_InitialAttenuation, Gen_SustainVolEnv:
+		return fmt.Sprintf("%.1f dB", v/10)
+	case Gen_ChorusEffectsSend, Gen_ReverbEffectsSend, Gen_Pan, Gen_SustainModEnv:
+		return fmt.Sprintf("%.1f %%", v/10)
+	case Gen_ModLfoToPitch, Gen_VibLfoToPitch, Gen_ModEnvToPitch, Gen_ModLfoToFilterFc,
+		Gen_ModEnvToFilterFc, Gen_FineTune, Gen_ScaleTuning:
+		return fmt.Sprintf("%d cents", amount)
+	case Gen_CoarseTune:
+		return fmt.Sprintf("%d semitones", amount)
+	case Gen_StartAddrsCoarseOffset, Gen_EndAddrsCoarseOffset,
+		Gen_StartloopAddrsCoarseOffset, Gen_EndloopAddrsCoarseOffset:
+		return fmt.Sprintf("%d points", int(amount)*32768)
+	case Gen_SampleModes:
+		return SampleMode(uint16(amount) & 3).String()
+	}
+	return ""
+}
+
+// modulatorDest describes a modulator's destination, either a generator or
+// another modulator of the zone.
+func modulatorDest(dest SFGenerator) string {
+	if dest&modDestLink != 0 {
+		return fmt.Sprintf("mod#%d", uint16(dest&^modDestLink))
+	}
+	return dest.String()
+}
+
+// DumpZone writes an aligned listing of a zone's generators and modulators,
+// with each generator's amount also shown in its natural unit.
+func DumpZone(w io.Writer, z Zone) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if z.Global != nil {
+		fmt.Fprintf(tw, "(global zone: %d generators, %d modulators)\n", len(z.Global.Generators), len(z.Global.Modulators))
+	}
+	for _, g := range z.Generators {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", g.GenOper.String(), g.GenAmount, generatorUnit(g.GenOper, g.GenAmount.AsInt16()))
+	}
+	for i, m := range z.Modulators {
+		fmt.Fprintf(tw, "mod#%d\t0x%04x\t-> %s\tamount %d\tamt src 0x%04x\ttransform %d\n",
+			i, uint16(m.ModSrcOper), modulatorDest(m.ModDestOper), m.ModAmount, uint16(m.ModAmtSrcOper), uint16(m.ModTransOper))
+	}
+
+	return tw.Flush()
+}
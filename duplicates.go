@@ -0,0 +1,123 @@
+package sf
+
+import (
+	"sort"
+	"strings"
+)
+
+// DuplicateKind is how a bank duplicates another.
+type DuplicateKind int
+
+const (
+	// Duplicate_Identical is a byte for byte copy.
+	Duplicate_Identical DuplicateKind = iota
+	// Duplicate_SameContent plays the same presets, but the files differ,
+	// for example in names, INFO or the order of their contents.
+	Duplicate_SameContent
+	// Duplicate_Subset has only presets the other bank also has.
+	Duplicate_Subset
+)
+
+func (k DuplicateKind) String() string {
+	switch k {
+	case Duplicate_Identical:
+		return "identical"
+	case Duplicate_SameContent:
+		return "same content"
+	case Duplicate_Subset:
+		return "subset"
+	}
+	return "unknown"
+}
+
+// Duplicate is a bank that can be deleted because another bank of the
+// catalog covers it.
+type Duplicate struct {
+	Kind DuplicateKind
+	// Bank is the redundant bank and Of the bank that covers it, both paths
+	// of the catalog.
+	Bank, Of string
+	// Size is the disk space deleting Bank frees.
+	Size int64
+}
+
+// FindDuplicates finds the banks of a catalog that other banks make
+// redundant, comparing file fingerprints for identical copies and preset
+// fingerprints for the rest. Each redundant bank is listed once, against a
+// bank that is not itself listed, preferring an identical copy, then one with
+// the same content, then the largest bank containing it. Of equal banks the
+// first by path is kept.
+func FindDuplicates(c *Catalog) []Duplicate {
+	var dups []Duplicate
+
+	// identical files
+	var kept []CatalogBank
+	first := map[string]string{}
+	for _, b := range c.Banks {
+		if of, ok := first[b.Fingerprint]; ok {
+			dups = append(dups, Duplicate{Duplicate_Identical, b.Path, of, b.Size})
+			continue
+		}
+		first[b.Fingerprint] = b.Path
+		kept = append(kept, b)
+	}
+
+	// same presets in different files
+	type content struct {
+		bank    CatalogBank
+		presets map[string]bool
+	}
+	var contents []content
+	firstContent := map[string]string{}
+	for _, b := range kept {
+		if b.Error != "" || len(b.Presets) == 0 {
+			continue
+		}
+		set := map[string]bool{}
+		for _, p := range b.Presets {
+			set[p.Fingerprint] = true
+		}
+		prints := make([]string, 0, len(set))
+		for fp := range set {
+			prints = append(prints, fp)
+		}
+		sort.Strings(prints)
+		key := strings.Join(prints, ",")
+
+		if of, ok := firstContent[key]; ok {
+			dups = append(dups, Duplicate{Duplicate_SameContent, b.Path, of, b.Size})
+			continue
+		}
+		firstContent[key] = b.Path
+		contents = append(contents, content{b, set})
+	}
+
+	// banks contained in a larger one
+	for _, a := range contents {
+		best := -1
+		for j, b := range contents {
+			if len(b.presets) <= len(a.presets) {
+				continue
+			}
+			if best >= 0 && len(b.presets) <= len(contents[best].presets) {
+				continue
+			}
+			subset := true
+			for fp := range a.presets {
+				if !b.presets[fp] {
+					subset = false
+					break
+				}
+			}
+			if subset {
+				best = j
+			}
+		}
+		if best >= 0 {
+			dups = append(dups, Duplicate{Duplicate_Subset, a.bank.Path, contents[best].bank.Path, a.bank.Size})
+		}
+	}
+
+	sort.SliceStable(dups, func(i, j int) bool { return dups[i].Bank < dups[j].Bank })
+	return dups
+}
@@ -0,0 +1,252 @@
+package sf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// EmbeddedPreset is a preset of an EmbeddedBank. Its regions are
+// Regions[FirstRegion : FirstRegion+RegionCount].
+type EmbeddedPreset struct {
+	Name          string
+	Bank, Program uint16
+
+	FirstRegion, RegionCount int
+}
+
+// EmbeddedRegion is a region flattened to the few parameters a small
+// wavetable engine plays, already converted to convenient units.
+type EmbeddedRegion struct {
+	KeyLo, KeyHi, VelLo, VelHi uint8
+
+	// RootKey is the key the sample plays at its own pitch, Tune the cents
+	// to add on top.
+	RootKey uint8
+	Tune    int16
+
+	// LoopMode is the sampleModes generator: 0 no loop, 1 loop, 3 loop
+	// until release.
+	LoopMode   uint8
+	SampleRate uint32
+
+	// Start, End, LoopStart and LoopEnd are sample point offsets into the
+	// bank's sample blob.
+	Start, End, LoopStart, LoopEnd uint32
+
+	// Attenuation is in centibels, Pan in tenths of a percent from -500
+	// (left) to 500 (right).
+	Attenuation int16
+	Pan         int16
+
+	// FilterFc is the lowpass cutoff in absolute cents, FilterQ its
+	// resonance in centibels.
+	FilterFc int16
+	FilterQ  int16
+
+	// The volume envelope, times in milliseconds and Sustain in centibels of
+	// attenuation. Key scaling of hold and decay is not kept.
+	Delay, Attack, Hold, Decay, Release uint16
+	Sustain                             int16
+
+	ExclusiveClass uint8
+}
+
+// EmbeddedBank is a bank reduced for microcontrollers that cannot parse RIFF:
+// tables of presets and regions pointing into one block of 16-bit samples.
+type EmbeddedBank struct {
+	Presets []EmbeddedPreset
+	Regions []EmbeddedRegion
+	Samples []int16
+}
+
+// envelopeMillis converts timecents to whole milliseconds, saturating.
+func envelopeMillis(tc int16) uint16 {
+	ms := math.Round(timecentsToSeconds(int(tc)) * 1000)
+	if ms > math.MaxUint16 {
+		return math.MaxUint16
+	}
+	return uint16(ms)
+}
+
+// ExportEmbedded flattens the given presets, indices into Hydra.Headers, into
+// an EmbeddedBank with only the samples they play. The 24-bit low bytes are
+// dropped.
+func ExportEmbedded(sf *SoundFont, presets []int) (*EmbeddedBank, error) {
+	sub, err := sf.Extract(presets)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &EmbeddedBank{}
+	if sub.Samples != nil {
+		b.Samples = sub.Samples.SamplesHigher
+	}
+	for i := 0; i+1 < len(sub.Hydra.Headers); i++ {
+		h := sub.Hydra.Headers[i]
+		regions, err := sub.Hydra.PresetRegions(i)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Presets = append(b.Presets, EmbeddedPreset{
+			Name:        trimName(h.PresetName),
+			Bank:        h.Bank,
+			Program:     h.Preset,
+			FirstRegion: len(b.Regions),
+			RegionCount: len(regions),
+		})
+		for _, r := range regions {
+			if r.Sample.isROM() {
+				return nil, fmt.Errorf("preset %q plays ROM sample %q", trimName(h.PresetName), trimName(r.Sample.SampleName))
+			}
+			keyLo, keyHi := rangeBytes(r.Gen(Gen_KeyRange))
+			velLo, velHi := rangeBytes(r.Gen(Gen_VelRange))
+			root := r.Sample.OriginalPitch
+			if k := r.Gen(Gen_OverridingRootKey); k >= 0 && k <= 127 {
+				root = uint8(k)
+			}
+			a := r.Addresses()
+			b.Regions = append(b.Regions, EmbeddedRegion{
+				KeyLo:          keyLo,
+				KeyHi:          keyHi,
+				VelLo:          velLo,
+				VelHi:          velHi,
+				RootKey:        root,
+				Tune:           clampInt16(100*int(r.Gen(Gen_CoarseTune)) + int(r.Gen(Gen_FineTune)) + int(r.Sample.PitchCorrection)),
+				LoopMode:       uint8(r.Gen(Gen_SampleModes) & 3),
+				SampleRate:     r.Sample.SampleRate,
+				Start:          a.Start,
+				End:            a.End,
+				LoopStart:      a.Startloop,
+				LoopEnd:        a.Endloop,
+				Attenuation:    r.Gen(Gen_InitialAttenuation),
+				Pan:            r.Gen(Gen_Pan),
+				FilterFc:       r.Gen(Gen_InitialFilterFc),
+				FilterQ:        r.Gen(Gen_InitialFilterQ),
+				Delay:          envelopeMillis(r.Gen(Gen_DelayVolEnv)),
+				Attack:         envelopeMillis(r.Gen(Gen_AttackVolEnv)),
+				Hold:           envelopeMillis(r.Gen(Gen_HoldVolEnv)),
+				Decay:          envelopeMillis(r.Gen(Gen_DecayVolEnv)),
+				Release:        envelopeMillis(r.Gen(Gen_ReleaseVolEnv)),
+				Sustain:        r.Gen(Gen_SustainVolEnv),
+				ExclusiveClass: uint8(r.Gen(Gen_ExclusiveClass)),
+			})
+		}
+	}
+	return b, nil
+}
+
+// WriteBlob writes the sample block as little endian 16-bit words, the
+// layout both WriteC and WriteGo expect.
+func (b *EmbeddedBank) WriteBlob(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, b.Samples)
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WriteC writes a C header declaring the bank's tables, with every symbol
+// prefixed by name. The samples are declared extern as name_samples, to be
+// linked from the blob, for example with objcopy or an assembler .incbin.
+func (b *EmbeddedBank) WriteC(w io.Writer, name string) error {
+	if !identifier.MatchString(name) {
+		return fmt.Errorf("%q is not a C identifier", name)
+	}
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "/* Generated from a SoundFont, do not edit. */\n")
+	fmt.Fprintf(bw, "#ifndef %s_H\n#define %s_H\n\n#include <stdint.h>\n\n", name, name)
+	fmt.Fprintf(bw, "typedef struct {\n\tconst char *name;\n\tuint16_t bank, program;\n\tuint16_t first_region, region_count;\n} %s_preset_t;\n\n", name)
+	fmt.Fprintf(bw, "typedef struct {\n"+
+		"\tuint8_t key_lo, key_hi, vel_lo, vel_hi;\n"+
+		"\tuint8_t root_key, loop_mode, exclusive_class;\n"+
+		"\tint16_t tune;\n"+
+		"\tuint32_t sample_rate;\n"+
+		"\tuint32_t start, end, loop_start, loop_end;\n"+
+		"\tint16_t attenuation, pan, filter_fc, filter_q;\n"+
+		"\tuint16_t delay_ms, attack_ms, hold_ms, decay_ms, release_ms;\n"+
+		"\tint16_t sustain;\n"+
+		"} %s_region_t;\n\n", name)
+
+	fmt.Fprintf(bw, "#define %s_SAMPLE_COUNT %d\n", name, len(b.Samples))
+	fmt.Fprintf(bw, "extern const int16_t %s_samples[%d];\n\n", name, len(b.Samples))
+
+	fmt.Fprintf(bw, "static const %s_preset_t %s_presets[%d] = {\n", name, name, len(b.Presets))
+	for _, p := range b.Presets {
+		fmt.Fprintf(bw, "\t{%s, %d, %d, %d, %d},\n", cString(p.Name), p.Bank, p.Program, p.FirstRegion, p.RegionCount)
+	}
+	fmt.Fprintf(bw, "};\n\n")
+
+	fmt.Fprintf(bw, "static const %s_region_t %s_regions[%d] = {\n", name, name, len(b.Regions))
+	for _, r := range b.Regions {
+		fmt.Fprintf(bw, "\t{%d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d},\n",
+			r.KeyLo, r.KeyHi, r.VelLo, r.VelHi, r.RootKey, r.LoopMode, r.ExclusiveClass, r.Tune, r.SampleRate,
+			r.Start, r.End, r.LoopStart, r.LoopEnd, r.Attenuation, r.Pan, r.FilterFc, r.FilterQ,
+			r.Delay, r.Attack, r.Hold, r.Decay, r.Release, r.Sustain)
+	}
+	fmt.Fprintf(bw, "};\n\n#endif\n")
+	return bw.Flush()
+}
+
+// cString quotes s as a C string literal. Bytes outside printable ASCII are
+// written as three digit octal escapes, which unlike hex escapes cannot run
+// into the characters after them, and '?' is escaped so no trigraph forms.
+func cString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', '?':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// WriteGo writes a Go source file for package pkg holding the bank's tables
+// as name + "Presets" and name + "Regions", with the blob, saved as
+// blobFile next to it, embedded as name + "Samples".
+func (b *EmbeddedBank) WriteGo(w io.Writer, pkg, name, blobFile string) error {
+	if !identifier.MatchString(pkg) || !identifier.MatchString(name) {
+		return fmt.Errorf("%q and %q must be Go identifiers", pkg, name)
+	}
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "// Code generated from a SoundFont. DO NOT EDIT.\n\npackage %s\n\nimport _ \"embed\"\n\n", pkg)
+	fmt.Fprintf(bw, "// %sSamples holds the little endian 16-bit sample points the regions\n// point into.\n//\n//go:embed %s\nvar %sSamples []byte\n\n", name, blobFile, name)
+
+	fmt.Fprintf(bw, "var %sPresets = []struct {\n\tName          string\n\tBank, Program uint16\n\tFirst, Count  int\n}{\n", name)
+	for _, p := range b.Presets {
+		fmt.Fprintf(bw, "\t{%q, %d, %d, %d, %d},\n", p.Name, p.Bank, p.Program, p.FirstRegion, p.RegionCount)
+	}
+	fmt.Fprintf(bw, "}\n\n")
+
+	fmt.Fprintf(bw, "var %sRegions = []struct {\n"+
+		"\tKeyLo, KeyHi, VelLo, VelHi, RootKey, LoopMode, ExclusiveClass uint8\n"+
+		"\tTune                                                          int16\n"+
+		"\tSampleRate, Start, End, LoopStart, LoopEnd                    uint32\n"+
+		"\tAttenuation, Pan, FilterFc, FilterQ                           int16\n"+
+		"\tDelay, Attack, Hold, Decay, Release                           uint16\n"+
+		"\tSustain                                                       int16\n"+
+		"}{\n", name)
+	for _, r := range b.Regions {
+		fmt.Fprintf(bw, "\t{%d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d},\n",
+			r.KeyLo, r.KeyHi, r.VelLo, r.VelHi, r.RootKey, r.LoopMode, r.ExclusiveClass, r.Tune,
+			r.SampleRate, r.Start, r.End, r.LoopStart, r.LoopEnd, r.Attenuation, r.Pan, r.FilterFc, r.FilterQ,
+			r.Delay, r.Attack, r.Hold, r.Decay, r.Release, r.Sustain)
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
@@ -0,0 +1,65 @@
+package sf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Piano", `"Piano"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"what??/", `"what\?\?/"`},
+		{"tab\there", `"tab\011here"`},
+		// an octal escape never swallows the digits after it
+		{"\x01" + "23", `"\00123"`},
+		{"Flügel", `"Fl\303\274gel"`},
+	}
+	for _, tt := range tests {
+		if got := cString(tt.in); got != tt.want {
+			t.Errorf("cString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// failingWriter accepts n bytes and then fails.
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriteCErrors(t *testing.T) {
+	b, err := ExportEmbedded(GenerateSineBank(2), []int{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Presets[0].Name = `Grand "Concert" Piano`
+
+	var out bytes.Buffer
+	if err := b.WriteC(&out, "bank"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `{"Grand \"Concert\" Piano", 0, 0, 0, 1},`) {
+		t.Errorf("preset name is not a C string literal in\n%s", out.String())
+	}
+
+	for _, n := range []int{0, out.Len() / 2, out.Len() - 1} {
+		if err := b.WriteC(&failingWriter{n}, "bank"); err == nil {
+			t.Errorf("WriteC to a writer failing after %d of %d bytes returned no error", n, out.Len())
+		}
+		if err := b.WriteGo(&failingWriter{n}, "bank", "bank", "bank.bin"); err == nil {
+			t.Errorf("WriteGo to a writer failing after %d bytes returned no error", n)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package sf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Encoder writes rendered audio to a file format. Channels hold samples in
+// -1..1 and must all have the same length.
+type Encoder interface {
+	Encode(w io.Writer, channels [][]float32, sampleRate int) error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		".wav":  WAVEncoder{},
+		".flac": FLACEncoder{},
+		".aif":  AIFFEncoder{},
+		".aiff": AIFFEncoder{},
+	}
+)
+
+// RegisterEncoder makes e the encoder for files with the extension ext (such
+// as ".ogg"), replacing any previous one. Extensions are case-insensitive.
+func RegisterEncoder(ext string, e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[strings.ToLower(ext)] = e
+}
+
+// EncoderFor returns the encoder registered for path's extension.
+func EncoderFor(path string) (Encoder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	e, ok := encoders[ext]
+	if !ok {
+		return nil, fmt.Errorf("no encoder registered for %q files", ext)
+	}
+	return e, nil
+}
+
+// checkChannels verifies the shape of audio handed to an encoder and returns
+// the number of frames.
+func checkChannels(channels [][]float32, sampleRate int) (int, error) {
+	if len(channels) == 0 {
+		return 0, fmt.Errorf("no channels to encode")
+	}
+	if sampleRate <= 0 {
+		return 0, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	frames := len(channels[0])
+	for i, ch := range channels {
+		if len(ch) != frames {
+			return 0, fmt.Errorf("channel %d has %d frames, expected %d", i, len(ch), frames)
+		}
+	}
+	return frames, nil
+}
+
+// quantize converts a sample in -1..1 to a signed integer of the given bit
+// depth, clipping anything outside the range.
+func quantize(v float32, bits uint) int32 {
+	max := float64(int32(1)<<(bits-1) - 1)
+	s := math.Round(float64(v) * max)
+	if s > max {
+		s = max
+	}
+	if s < -max-1 {
+		s = -max - 1
+	}
+	return int32(s)
+}
+
+// WAVEncoder writes PCM RIFF WAVE files.
+type WAVEncoder struct {
+	// BitDepth is 16 or 24. Zero means 16.
+	BitDepth int
+}
+
+func (e WAVEncoder) Encode(w io.Writer, channels [][]float32, sampleRate int) error {
+	frames, err := checkChannels(channels, sampleRate)
+	if err != nil {
+		return err
+	}
+	depth := e.BitDepth
+	if depth == 0 {
+		depth = 16
+	}
+	if depth != 16 && depth != 24 {
+		return fmt.Errorf("unsupported WAV bit depth %d", depth)
+	}
+
+	blockAlign := len(channels) * depth / 8
+	dataSize := frames * blockAlign
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		[4]byte{'R', 'I', 'F', 'F'}, uint32(36 + dataSize + dataSize%2), [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, 16, 1, uint16(len(channels)), uint32(sampleRate),
+		uint32(sampleRate * blockAlign), uint16(blockAlign), uint16(depth),
+		[4]byte{'d', 'a', 't', 'a'}, uint32(dataSize),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, blockAlign*1024)
+	for i := 0; i < frames; i++ {
+		for _, ch := range channels {
+			s := quantize(ch[i], uint(depth))
+			buf = append(buf, byte(s), byte(s>>8))
+			if depth == 24 {
+				buf = append(buf, byte(s>>16))
+			}
+		}
+		if len(buf) >= blockAlign*1024 || i == frames-1 {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+
+	// the data chunk is padded to an even size
+	if dataSize%2 != 0 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AIFFEncoder writes 16-bit PCM AIFF files.
+type AIFFEncoder struct{}
+
+func (AIFFEncoder) Encode(w io.Writer, channels [][]float32, sampleRate int) error {
+	frames, err := checkChannels(channels, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	dataSize := frames * len(channels) * 2
+	header := struct {
+		FORM       [4]byte
+		Size       uint32
+		AIFF       [4]byte
+		COMM       [4]byte
+		CommSize   uint32
+		Channels   uint16
+		Frames     uint32
+		SampleSize uint16
+		SampleRate [10]byte
+		SSND       [4]byte
+		SsndSize   uint32
+		Offset     uint32
+		BlockSize  uint32
+	}{
+		[4]byte{'F', 'O', 'R', 'M'}, uint32(4 + 26 + 16 + dataSize + dataSize%2), [4]byte{'A', 'I', 'F', 'F'},
+		[4]byte{'C', 'O', 'M', 'M'}, 18, uint16(len(channels)), uint32(frames), 16, extended(sampleRate),
+		[4]byte{'S', 'S', 'N', 'D'}, uint32(8 + dataSize), 0, 0,
+	}
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, len(channels)*2*1024)
+	for i := 0; i < frames; i++ {
+		for _, ch := range channels {
+			s := quantize(ch[i], 16)
+			buf = append(buf, byte(s>>8), byte(s))
+		}
+		if len(buf) == cap(buf) || i == frames-1 {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	return nil
+}
+
+// extended encodes a positive integer as the 80-bit IEEE 754 extended
+// precision float AIFF uses for its sample rate.
+func extended(v int) [10]byte {
+	var b [10]byte
+	if v <= 0 {
+		return b
+	}
+	exp := 63
+	m := uint64(v)
+	for m&(1<<63) == 0 {
+		m <<= 1
+		exp--
+	}
+	binary.BigEndian.PutUint16(b[0:], uint16(16383+exp))
+	binary.BigEndian.PutUint64(b[2:], m)
+	return b
+}
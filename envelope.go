@@ -0,0 +1,127 @@
+package sf
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Envelope is a region's volume or modulation envelope with its generators
+// converted to seconds and a sustain level.
+type Envelope struct {
+	Delay, Attack, Hold, Decay, Release float64
+
+	// Sustain is the level, 0 to 1, held after the decay.
+	Sustain float64
+
+	// Volume is set for the volume envelope, whose decay and release fall
+	// linearly in dB: their times are how long a 100 dB fall takes. The
+	// modulation envelope falls linearly and its times cover the full 1 to 0
+	// range.
+	Volume bool
+}
+
+// regionEnvelope reads the envelope whose first generator is delay, the
+// others following in the order the spec numbers them.
+func (r *Region) regionEnvelope(delay SFGenerator, volume bool) Envelope {
+	gen := func(i SFGenerator) int { return int(r.Gen(delay + i)) }
+	keyScale := 60 - int(r.Key)
+
+	e := Envelope{
+		Delay:   timecentsToSeconds(gen(0)),
+		Attack:  timecentsToSeconds(gen(1)),
+		Hold:    timecentsToSeconds(gen(2) + gen(6)*keyScale),
+		Decay:   timecentsToSeconds(gen(3) + gen(7)*keyScale),
+		Release: timecentsToSeconds(gen(5)),
+		Volume:  volume,
+	}
+	sustain := math.Min(math.Max(float64(gen(4)), 0), 1000)
+	if volume {
+		// centibels of attenuation, 100 dB or more is silence
+		if sustain >= 1000 {
+			e.Sustain = 0
+		} else {
+			e.Sustain = centibelsToGain(sustain)
+		}
+	} else {
+		// tenths of a percent below full
+		e.Sustain = 1 - sustain/1000
+	}
+	return e
+}
+
+// VolumeEnvelope returns the region's volume envelope.
+func (r *Region) VolumeEnvelope() Envelope {
+	return r.regionEnvelope(Gen_DelayVolEnv, true)
+}
+
+// ModulationEnvelope returns the region's modulation envelope.
+func (r *Region) ModulationEnvelope() Envelope {
+	return r.regionEnvelope(Gen_DelayModEnv, false)
+}
+
+// fall moves level down for t seconds at the rate the envelope's decay and
+// release use, given the stage's time.
+func (e Envelope) fall(level, t, stage float64) float64 {
+	if stage <= 0 {
+		return 0
+	}
+	if !e.Volume {
+		return math.Max(level-t/stage, 0)
+	}
+	if level <= 0 {
+		return 0
+	}
+	db := 20*math.Log10(level) - 100*t/stage
+	if db <= -100 {
+		return 0
+	}
+	return math.Pow(10, db/20)
+}
+
+// Level returns the envelope's level, 0 to 1, t seconds after note-on for a
+// note released at noteOff seconds. A negative noteOff holds the note.
+func (e Envelope) Level(t, noteOff float64) float64 {
+	held := func(t float64) float64 {
+		switch {
+		case t < e.Delay:
+			return 0
+		case t < e.Delay+e.Attack:
+			return (t - e.Delay) / e.Attack
+		case t < e.Delay+e.Attack+e.Hold:
+			return 1
+		}
+		return math.Max(e.fall(1, t-e.Delay-e.Attack-e.Hold, e.Decay), e.Sustain)
+	}
+
+	if noteOff < 0 || t < noteOff {
+		return held(t)
+	}
+	return e.fall(held(noteOff), t-noteOff, e.Release)
+}
+
+// SVG plots the envelope over duration seconds as a line width x height
+// units large, with the note released at noteOff (negative to hold it) and a
+// dashed line marking the release.
+func (e Envelope) SVG(w io.Writer, width, height int, duration, noteOff float64) error {
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n"+
+		`<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height, width, height, width, height); err != nil {
+		return err
+	}
+	if duration > 0 && width > 0 {
+		x := func(t float64) float64 { return t / duration * float64(width) }
+		y := func(level float64) float64 { return (1 - level) * float64(height) }
+
+		if noteOff >= 0 && noteOff <= duration {
+			fmt.Fprintf(w, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d" stroke="#999999" stroke-dasharray="4 4"/>`+"\n", x(noteOff), x(noteOff), height)
+		}
+		fmt.Fprint(w, `<polyline fill="none" stroke="#1f4e79" stroke-width="1.5" points="`)
+		for px := 0; px <= width; px++ {
+			t := float64(px) / float64(width) * duration
+			fmt.Fprintf(w, "%.1f,%.1f ", x(t), y(e.Level(t, noteOff)))
+		}
+		fmt.Fprint(w, `"/>`+"\n")
+	}
+	_, err := fmt.Fprint(w, "</svg>\n")
+	return err
+}
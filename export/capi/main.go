@@ -0,0 +1,103 @@
+//go:build cgo
+
+// Command capi builds a C shared library over the sf package, for hosts
+// such as plugins and game engines that cannot link Go directly:
+//
+//	go build -buildmode=c-shared -o libsf.so ./export/capi
+//
+// A loaded bank is an opaque handle, released with sf_free. Functions that
+// return text copy it into a caller supplied buffer, NUL terminated and
+// truncated to fit, and return the full length, like snprintf.
+package main
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/Alextopher/sf"
+)
+
+func main() {}
+
+// copyString copies s into the buffer of size bytes at buf and returns the
+// length of s.
+func copyString(s string, buf *C.char, size C.size_t) C.int {
+	if buf != nil && size > 0 {
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(size))
+		n := copy(dst[:len(dst)-1], s)
+		dst[n] = 0
+	}
+	return C.int(len(s))
+}
+
+// register turns the result of a load into a handle, or 0 with the error
+// copied into errbuf.
+func register(bank *sf.SoundFont, err error, errbuf *C.char, errsize C.size_t) C.uintptr_t {
+	if err != nil {
+		copyString(err.Error(), errbuf, errsize)
+		return 0
+	}
+	return C.uintptr_t(cgo.NewHandle(bank))
+}
+
+// bankOf returns the bank behind a handle.
+func bankOf(h C.uintptr_t) *sf.SoundFont {
+	return cgo.Handle(h).Value().(*sf.SoundFont)
+}
+
+// presets returns a bank's preset headers without the terminal record.
+func presets(bank *sf.SoundFont) []sf.PresetHeader {
+	headers := bank.Hydra.Headers
+	if len(headers) > 0 {
+		headers = headers[:len(headers)-1]
+	}
+	return headers
+}
+
+//export sf_load
+func sf_load(path *C.char, errbuf *C.char, errsize C.size_t) C.uintptr_t {
+	bank, err := sf.LoadFile(C.GoString(path))
+	return register(bank, err, errbuf, errsize)
+}
+
+//export sf_load_memory
+func sf_load_memory(data unsafe.Pointer, size C.size_t, errbuf *C.char, errsize C.size_t) C.uintptr_t {
+	bank, err := sf.ReadSoundFont(bytes.NewReader(C.GoBytes(data, C.int(size))))
+	return register(bank, err, errbuf, errsize)
+}
+
+//export sf_free
+func sf_free(h C.uintptr_t) {
+	cgo.Handle(h).Delete()
+}
+
+//export sf_preset_count
+func sf_preset_count(h C.uintptr_t) C.int {
+	return C.int(len(presets(bankOf(h))))
+}
+
+// sf_preset looks up preset i, returning its bank and program numbers and
+// copying its name into buf. It returns -1 when there is no preset i.
+//
+//export sf_preset
+func sf_preset(h C.uintptr_t, i C.int, bank, program *C.int, buf *C.char, size C.size_t) C.int {
+	headers := presets(bankOf(h))
+	if i < 0 || int(i) >= len(headers) {
+		return -1
+	}
+	p := headers[i]
+	if bank != nil {
+		*bank = C.int(p.Bank)
+	}
+	if program != nil {
+		*program = C.int(p.Preset)
+	}
+	return copyString(p.Name(), buf, size)
+}
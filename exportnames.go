@@ -0,0 +1,145 @@
+package sf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NamePolicy turns sample, instrument and preset names into file names that
+// are safe to write on every common filesystem and unique within a
+// directory. The zero value is the default policy.
+type NamePolicy struct {
+	// Replacement is substituted for illegal characters. Defaults to "_".
+	Replacement string
+
+	// Illegal reports characters that may not appear in a file name. Defaults
+	// to control characters and the characters Windows forbids.
+	Illegal func(r rune) bool
+
+	// CaseSensitive treats names differing only in case as distinct. Leave it
+	// unset when the output may land on a case-insensitive filesystem.
+	CaseSensitive bool
+
+	// MaxLength limits the base name, in bytes, before the extension. Zero
+	// means 64.
+	MaxLength int
+}
+
+// NameMapping records the file name chosen for an exported object.
+type NameMapping struct {
+	Original string
+	File     string
+}
+
+// Changed reports whether the file name differs from the original name plus
+// the extension.
+func (m NameMapping) Changed(ext string) bool {
+	return m.File != m.Original+ext
+}
+
+func defaultIllegal(r rune) bool {
+	return unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r)
+}
+
+// windowsReserved are device names Windows refuses as file names, with or
+// without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Sanitize returns name with illegal characters replaced, surrounding spaces
+// and trailing dots removed, and cut to MaxLength. Empty names get a suffix,
+// as do reserved device names; Windows reserves those whatever their case
+// and whatever follows the first dot, so "nul.sf2" is renamed too.
+func (p NamePolicy) Sanitize(name string) string {
+	max := p.MaxLength
+	if max == 0 {
+		max = 64
+	}
+	return p.sanitize(name, max)
+}
+
+// sanitize is Sanitize with the length limit given.
+func (p NamePolicy) sanitize(name string, max int) string {
+	repl := p.Replacement
+	if repl == "" {
+		repl = "_"
+	}
+	illegal := p.Illegal
+	if illegal == nil {
+		illegal = defaultIllegal
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		if illegal(r) || r == unicode.ReplacementChar {
+			b.WriteString(repl)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	s := cutName(strings.TrimSpace(b.String()), max)
+
+	stem, rest := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		stem, rest = s[:i], s[i:]
+	}
+	if s == "" || windowsReserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		// make room for the suffix by shortening what follows the stem
+		s = stem + repl + cutName(rest, max-len(stem)-len(repl))
+	}
+	return s
+}
+
+// cutName cuts s to at most max bytes on a rune boundary, dropping the
+// trailing dots and spaces Windows would strip.
+func cutName(s string, max int) string {
+	if len(s) > max {
+		cut := 0
+		for i := range s {
+			if i > max {
+				break
+			}
+			cut = i
+		}
+		s = s[:cut]
+	}
+	return strings.TrimRight(s, ". ")
+}
+
+// Map chooses a file name with extension ext for each name, in order. Names
+// that collide after sanitization are numbered "name-2", "name-3" and so on,
+// shortened so the numbered names still fit MaxLength.
+// The result has one mapping per input name and serves as the report of
+// what was renamed.
+func (p NamePolicy) Map(names []string, ext string) []NameMapping {
+	used := make(map[string]bool, len(names))
+	fold := func(s string) string {
+		if p.CaseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+
+	max := p.MaxLength
+	if max == 0 {
+		max = 64
+	}
+
+	mappings := make([]NameMapping, len(names))
+	for i, name := range names {
+		file := p.sanitize(name, max) + ext
+		for n := 2; used[fold(file)]; n++ {
+			suffix := fmt.Sprintf("-%d", n)
+			file = p.sanitize(name, max-len(suffix)) + suffix + ext
+		}
+		used[fold(file)] = true
+		mappings[i] = NameMapping{Original: name, File: file}
+	}
+	return mappings
+}
@@ -0,0 +1,63 @@
+package sf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		policy NamePolicy
+		name   string
+		want   string
+	}{
+		{NamePolicy{}, "Grand Piano", "Grand Piano"},
+		{NamePolicy{}, ` a/b:c?. `, "a_b_c_"},
+		{NamePolicy{}, "", "_"},
+		{NamePolicy{}, "CON", "CON_"},
+		{NamePolicy{}, "con", "con_"},
+		{NamePolicy{}, "CON.txt", "CON_.txt"},
+		{NamePolicy{}, "nul.sf2", "nul_.sf2"},
+		{NamePolicy{}, "Lpt1.a.b", "Lpt1_.a.b"},
+		{NamePolicy{}, "CONSOLE.txt", "CONSOLE.txt"},
+		{NamePolicy{}, ".hidden", ".hidden"},
+		{NamePolicy{MaxLength: 5}, "Strings", "Strin"},
+		{NamePolicy{MaxLength: 5}, "abcd. ef", "abcd"},
+		{NamePolicy{MaxLength: 7}, "aux.wave", "aux_.wa"},
+		{NamePolicy{MaxLength: 4}, "héllo", "hél"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Sanitize(tt.name); got != tt.want {
+			t.Errorf("Sanitize(%q) with MaxLength %d = %q, want %q", tt.name, tt.policy.MaxLength, got, tt.want)
+		}
+	}
+}
+
+func TestMapNames(t *testing.T) {
+	// every name cuts to "Trumpet", so the numbered ones give up a byte or
+	// two of it to stay within MaxLength
+	p := NamePolicy{MaxLength: 8}
+	names := []string{"Trumpet 1", "trumpet 1", "Trumpet 2", "Trumpet 1?"}
+	want := []string{"Trumpet.wav", "trumpe-2.wav", "Trumpe-3.wav", "Trumpe-4.wav"}
+
+	got := p.Map(names, ".wav")
+	if len(got) != len(names) {
+		t.Fatalf("got %d mappings for %d names", len(got), len(names))
+	}
+	for i, m := range got {
+		if m.Original != names[i] || m.File != want[i] {
+			t.Errorf("mapping %d is %q -> %q, want %q -> %q", i, m.Original, m.File, names[i], want[i])
+		}
+	}
+
+	// numbering never lengthens a name past MaxLength
+	long := make([]string, 12)
+	for i := range long {
+		long[i] = "Strings"
+	}
+	for _, m := range (NamePolicy{MaxLength: 7}).Map(long, ".wav") {
+		if base := strings.TrimSuffix(m.File, ".wav"); len(base) > 7 {
+			t.Errorf("%q is longer than 7 bytes", base)
+		}
+	}
+}
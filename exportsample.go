@@ -0,0 +1,56 @@
+package sf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// ExportSample writes the data of s, from Start up to End, to a mono audio
+// file at path. The format is picked by path's extension through EncoderFor:
+// ".wav", ".flac", ".aif" and ".aiff" are built in, and other formats such as
+// ".ogg" can be added with RegisterEncoder. 16-bit data comes out bit exact
+// from the built-in encoders; the low bytes of 24-bit banks only survive in a
+// WAV written by a 24-bit WAVEncoder. ROM samples have no data to export.
+func (sf *SoundFont) ExportSample(path string, s SampleHeader) error {
+	if s.isROM() {
+		return fmt.Errorf("sample %q lives in ROM", trimName(s.SampleName))
+	}
+	enc, err := EncoderFor(path)
+	if err != nil {
+		return err
+	}
+	if s.SampleRate == 0 {
+		return fmt.Errorf("sample %q has no sample rate", trimName(s.SampleName))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := enc.Encode(w, [][]float32{sf.sampleFloats(s)}, int(s.SampleRate)); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sampleFloats converts the data of s to floats in -1..1, scaled so that
+// quantize gives back the original points.
+func (sf *SoundFont) sampleFloats(s SampleHeader) []float32 {
+	higher, lower := sf.SampleData(s)
+	out := make([]float32, len(higher))
+	for i, v := range higher {
+		if lower != nil {
+			out[i] = float32(int32(v)<<8|int32(uint8(lower[i]))) / (1<<23 - 1)
+		} else {
+			out[i] = float32(v) / (1<<15 - 1)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package sf
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportSample(t *testing.T) {
+	bank := GenerateNoiseBank(1, 1)
+	s := bank.Hydra.Samples[0]
+	want, _ := bank.SampleData(s)
+	dir := t.TempDir()
+
+	read := func(name string) []byte {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := bank.ExportSample(path, s); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	check := func(format string, got []int16) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s holds %d points, want %d", format, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s point %d is %d, want %d", format, i, got[i], want[i])
+			}
+		}
+	}
+
+	wav := read("s.wav")[44:]
+	points := make([]int16, len(wav)/2)
+	for i := range points {
+		points[i] = int16(binary.LittleEndian.Uint16(wav[2*i:]))
+	}
+	check("WAV", points)
+
+	aiff := read("s.AIFF")
+	if rate := extended(int(s.SampleRate)); string(aiff[28:38]) != string(rate[:]) {
+		t.Errorf("AIFF sample rate is % x, want % x", aiff[28:38], rate)
+	}
+	ssnd := aiff[54:]
+	points = make([]int16, len(ssnd)/2)
+	for i := range points {
+		points[i] = int16(binary.BigEndian.Uint16(ssnd[2*i:]))
+	}
+	check("AIFF", points)
+
+	channels, rate, err := decodeFLAC(read("s.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != int(s.SampleRate) || len(channels) != 1 {
+		t.Fatalf("FLAC has %d channels at %d Hz", len(channels), rate)
+	}
+	points = make([]int16, len(channels[0]))
+	for i, v := range channels[0] {
+		points[i] = int16(v)
+	}
+	check("FLAC", points)
+
+	if err := bank.ExportSample(filepath.Join(dir, "s.ogg"), s); err == nil {
+		t.Error("exported to a format with no encoder")
+	}
+	rom := s
+	rom.SampleType = SampleType_Rom_Mono
+	if err := bank.ExportSample(filepath.Join(dir, "rom.wav"), rom); err == nil {
+		t.Error("exported a ROM sample")
+	}
+}
+
+func TestExtended(t *testing.T) {
+	// 44100 Hz as AIFF files store it
+	want := [10]byte{0x40, 0x0e, 0xac, 0x44}
+	if got := extended(44100); got != want {
+		t.Errorf("extended(44100) = % x, want % x", got, want)
+	}
+}
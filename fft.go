@@ -0,0 +1,61 @@
+package sf
+
+import (
+	"math"
+	"math/bits"
+)
+
+// fft computes the discrete Fourier transform of x in place. len(x) must be a
+// power of two.
+func fft(x []complex128) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// bit reversal permutation
+	shift := 64 - uint(bits.Len(uint(n-1)))
+	for i := 0; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := 0; k < size/2; k++ {
+				w := complex(math.Cos(step*float64(k)), math.Sin(step*float64(k)))
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+			}
+		}
+	}
+}
+
+// hann returns the coefficients of a Hann window of length n.
+func hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
+
+// magnitudes windows frame, transforms it and returns the magnitudes of the
+// first half of the spectrum. len(frame) must be a power of two.
+func magnitudes(frame []float64, window []float64) []float64 {
+	x := make([]complex128, len(frame))
+	for i, v := range frame {
+		x[i] = complex(v*window[i], 0)
+	}
+	fft(x)
+
+	mags := make([]float64, len(x)/2+1)
+	for i := range mags {
+		re, im := real(x[i]), imag(x[i])
+		mags[i] = math.Sqrt(re*re + im*im)
+	}
+	return mags
+}
@@ -0,0 +1,152 @@
+package sf
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SampleFileInfo is what a sample's file name says about where it belongs.
+// Fields the pattern does not capture are -1 (or empty for Name).
+type SampleFileInfo struct {
+	Name       string
+	Key        int
+	Velocity   int
+	RoundRobin int
+}
+
+// NamePattern extracts SampleFileInfo from file names.
+//
+// A pattern is matched against the file name without its directory and
+// extension. It is literal text with these placeholders:
+//
+//	{name}      any text, the sample group name
+//	{note}      a note name such as C4, F#2 or Bb-1 (C4 is key 60)
+//	{key}       a MIDI key number
+//	{vel}       a velocity from 0 to 127
+//	{rr}        a round-robin index
+//	{*}         any text, ignored
+//
+// Matching is case-insensitive, so "Piano_{note}_vel{vel}" matches
+// "piano_C4_VEL96.wav".
+type NamePattern struct {
+	re     *regexp.Regexp
+	fields []string
+}
+
+var placeholder = regexp.MustCompile(`\{(name|note|key|vel|rr|\*)\}`)
+
+// CompileNamePattern compiles a file name pattern.
+func CompileNamePattern(pattern string) (*NamePattern, error) {
+	p := &NamePattern{}
+
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	last := 0
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern[last:loc[0]]))
+		last = loc[1]
+
+		field := pattern[loc[2]:loc[3]]
+		switch field {
+		case "name", "*":
+			expr.WriteString("(.+?)")
+		case "note":
+			expr.WriteString(`([a-g][#sb]?-?\d)`)
+		case "key", "vel", "rr":
+			expr.WriteString(`(\d+)`)
+		}
+		p.fields = append(p.fields, field)
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	p.re = re
+	return p, nil
+}
+
+// ParseNoteName converts a note name such as "C4", "F#2", "Eb3" or "A-1" to a
+// MIDI key number, with C4 being key 60. 's' is accepted for sharp since '#'
+// is awkward in file names.
+func ParseNoteName(note string) (int, error) {
+	n := strings.ToLower(note)
+	if len(n) < 2 {
+		return 0, fmt.Errorf("invalid note name %q", note)
+	}
+
+	pc := strings.IndexByte("c d ef g a b", n[0])
+	if pc < 0 || n[0] == ' ' {
+		return 0, fmt.Errorf("invalid note name %q", note)
+	}
+	n = n[1:]
+
+	switch n[0] {
+	case '#', 's':
+		pc++
+		n = n[1:]
+	case 'b':
+		// 'b' followed by the octave is a flat, "b4" alone was handled as B
+		if len(n) > 1 {
+			pc--
+			n = n[1:]
+		}
+	}
+
+	octave, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, fmt.Errorf("invalid octave in note name %q", note)
+	}
+
+	key := (octave+1)*12 + pc
+	if key < 0 || key > 127 {
+		return 0, fmt.Errorf("note %q is outside the MIDI range", note)
+	}
+	return key, nil
+}
+
+// Parse extracts the mapping encoded in a file name.
+func (p *NamePattern) Parse(filename string) (SampleFileInfo, error) {
+	info := SampleFileInfo{Key: -1, Velocity: -1, RoundRobin: -1}
+
+	base := filepath.Base(filename)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	m := p.re.FindStringSubmatch(base)
+	if m == nil {
+		return info, fmt.Errorf("%q does not match the name pattern", filename)
+	}
+
+	for i, field := range p.fields {
+		v := m[i+1]
+		var err error
+		switch field {
+		case "name":
+			info.Name = v
+		case "note":
+			info.Key, err = ParseNoteName(v)
+		case "key":
+			info.Key, err = strconv.Atoi(v)
+			if err == nil && info.Key > 127 {
+				err = fmt.Errorf("key %d is outside the MIDI range", info.Key)
+			}
+		case "vel":
+			info.Velocity, err = strconv.Atoi(v)
+			if err == nil && info.Velocity > 127 {
+				err = fmt.Errorf("velocity %d is outside the MIDI range", info.Velocity)
+			}
+		case "rr":
+			info.RoundRobin, err = strconv.Atoi(v)
+		}
+		if err != nil {
+			return info, fmt.Errorf("%q: %w", filename, err)
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,336 @@
+package sf
+
+import "fmt"
+
+// FitStep is one way FitToBudget can shrink a bank.
+type FitStep int
+
+const (
+	// Fit_Drop24Bit discards the sm24 low bytes, leaving 16-bit samples.
+	Fit_Drop24Bit FitStep = iota
+	// Fit_DropVelocityLayers keeps only the loudest of the instrument zones
+	// that split a key range by velocity, stretched over all velocities, and
+	// drops the samples no longer played.
+	Fit_DropVelocityLayers
+	// Fit_Resample halves the sample rate of every sample that stays at or
+	// above 11025 Hz. It is repeated while the bank is over budget.
+	Fit_Resample
+)
+
+func (s FitStep) String() string {
+	switch s {
+	case Fit_Drop24Bit:
+		return "drop 24-bit"
+	case Fit_DropVelocityLayers:
+		return "drop velocity layers"
+	case Fit_Resample:
+		return "resample"
+	}
+	return "unknown"
+}
+
+// DefaultFitSteps gives up bit depth first, then velocity layers, then
+// bandwidth.
+var DefaultFitSteps = []FitStep{Fit_Drop24Bit, Fit_DropVelocityLayers, Fit_Resample}
+
+// minFitSampleRate is the lowest rate Fit_Resample takes a sample to.
+const minFitSampleRate = 11025
+
+// FitSacrifice is one change FitToBudget made.
+type FitSacrifice struct {
+	Step   FitStep
+	Detail string
+	// Saved is the bytes the change saved.
+	Saved int64
+}
+
+// FitReport lists what FitToBudget gave up.
+type FitReport struct {
+	Before, After int64
+	Sacrifices    []FitSacrifice
+}
+
+// bankBytes estimates a bank's size on disk from its sample points and
+// hydra tables.
+func bankBytes(sf *SoundFont) int64 {
+	var n int64
+	if sf.Samples != nil {
+		n += 2*int64(len(sf.Samples.SamplesHigher)) + int64(len(sf.Samples.SamplesLower))
+	}
+	if sf.Hydra != nil {
+		n += sliceBytes(sf.Hydra)
+	}
+	return n
+}
+
+// FitToBudget shrinks sf in place until it takes at most maxBytes, applying
+// the steps in order, each as often as it helps, and stopping as soon as the
+// bank fits. A nil steps uses DefaultFitSteps. When every step is exhausted
+// and the bank is still too large, the report and an error are returned; the
+// changes made are kept.
+func FitToBudget(sf *SoundFont, maxBytes int64, steps []FitStep) (FitReport, error) {
+	if steps == nil {
+		steps = DefaultFitSteps
+	}
+
+	size := bankBytes(sf)
+	report := FitReport{Before: size}
+	for _, step := range steps {
+		for size > maxBytes {
+			detail, err := sf.fitStep(step)
+			if err != nil {
+				return report, err
+			}
+			if detail == "" {
+				break
+			}
+			after := bankBytes(sf)
+			report.Sacrifices = append(report.Sacrifices, FitSacrifice{step, detail, size - after})
+			size = after
+		}
+	}
+	report.After = size
+
+	if size > maxBytes {
+		return report, fmt.Errorf("bank still needs %d bytes, more than the budget of %d", size, maxBytes)
+	}
+	return report, nil
+}
+
+// fitStep applies step once and describes what it gave up, or returns ""
+// when there was nothing left for it to do.
+func (sf *SoundFont) fitStep(step FitStep) (string, error) {
+	switch step {
+	case Fit_Drop24Bit:
+		if sf.Samples == nil || sf.Samples.SamplesLower == nil {
+			return "", nil
+		}
+		n := len(sf.Samples.SamplesLower)
+		sf.Samples.SamplesLower = nil
+		return fmt.Sprintf("dropped the low 8 bits of %d sample points", n), nil
+	case Fit_DropVelocityLayers:
+		return sf.dropVelocityLayers()
+	case Fit_Resample:
+		return sf.halveSampleRates()
+	}
+	return "", fmt.Errorf("unknown fit step %d", step)
+}
+
+// dropVelocityLayers keeps, for every key range of an instrument split by
+// velocity, the zone reaching the highest velocity.
+func (sf *SoundFont) dropVelocityLayers() (string, error) {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return "", err
+	}
+
+	dropped, touched := 0, 0
+	for i, inst := range l.Instruments {
+		best := map[[2]uint8]int{}
+		layers := map[[2]uint8]int{}
+		for j, z := range inst.Zones {
+			if _, ok := z.terminal(Gen_SampleID); !ok {
+				continue
+			}
+			lo, hi, _ := zoneKeyRange(z)
+			k := [2]uint8{lo, hi}
+			layers[k]++
+			if b, ok := best[k]; !ok || zoneVelHigh(z) > zoneVelHigh(inst.Zones[b]) {
+				best[k] = j
+			}
+		}
+
+		var zones []Zone
+		for j, z := range inst.Zones {
+			if _, ok := z.terminal(Gen_SampleID); !ok {
+				zones = append(zones, z)
+				continue
+			}
+			lo, hi, _ := zoneKeyRange(z)
+			k := [2]uint8{lo, hi}
+			switch {
+			case layers[k] < 2:
+				zones = append(zones, z)
+			case best[k] == j:
+				setGenerator(&z, Gen_VelRange, makeRange(0, 127))
+				zones = append(zones, z)
+			default:
+				dropped++
+			}
+		}
+		if len(zones) != len(inst.Zones) {
+			touched++
+			l.Instruments[i].Zones = zones
+		}
+	}
+	if dropped == 0 {
+		return "", nil
+	}
+
+	if sf.Hydra, err = l.Pack(); err != nil {
+		return "", err
+	}
+	if err := sf.compact(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("dropped %d velocity layers from %d instruments", dropped, touched), nil
+}
+
+// compact rebuilds the bank with only the instruments and samples its
+// presets use.
+func (sf *SoundFont) compact() error {
+	presets := make([]int, 0, len(sf.Hydra.Headers))
+	for i := 0; i+1 < len(sf.Hydra.Headers); i++ {
+		presets = append(presets, i)
+	}
+	out, err := sf.Extract(presets)
+	if err != nil {
+		return err
+	}
+	sf.Hydra, sf.Samples = out.Hydra, out.Samples
+	return nil
+}
+
+// zoneVelHigh returns the top of a zone's velocity range.
+func zoneVelHigh(z Zone) uint8 {
+	for _, g := range z.Generators {
+		if g.GenOper == Gen_VelRange {
+			_, hi := g.GenAmount.AsRange()
+			return hi
+		}
+	}
+	return 127
+}
+
+// setGenerator sets op in a zone, adding it in spec order when missing.
+func setGenerator(z *Zone, op SFGenerator, amount int16) {
+	for i, g := range z.Generators {
+		if g.GenOper == op {
+			z.Generators[i].GenAmount = GenAmount(amount)
+			return
+		}
+	}
+	z.Generators = append(z.Generators, Generator{op, GenAmount(amount)})
+	orderGenerators(z.Generators)
+}
+
+// zoneGenerator returns op's amount in a zone, or 0 when it is not set.
+func zoneGenerator(z Zone, op SFGenerator) int16 {
+	for _, g := range z.Generators {
+		if g.GenOper == op {
+			return g.GenAmount.AsInt16()
+		}
+	}
+	return 0
+}
+
+// halveOffsets halves the sample address offsets of a zone whose sample was
+// resampled to half the rate.
+func halveOffsets(z *Zone) {
+	for _, pair := range [][2]SFGenerator{
+		{Gen_StartAddrsOffset, Gen_StartAddrsCoarseOffset},
+		{Gen_EndAddrsOffset, Gen_EndAddrsCoarseOffset},
+		{Gen_StartloopAddrsOffset, Gen_StartloopAddrsCoarseOffset},
+		{Gen_EndloopAddrsOffset, Gen_EndloopAddrsCoarseOffset},
+	} {
+		total := int(zoneGenerator(*z, pair[0])) + 32768*int(zoneGenerator(*z, pair[1]))
+		if total == 0 {
+			continue
+		}
+		total /= 2
+		coarse := total / 32768
+		setGenerator(z, pair[0], int16(total-coarse*32768))
+		if coarse != 0 || zoneGenerator(*z, pair[1]) != 0 {
+			setGenerator(z, pair[1], int16(coarse))
+		}
+	}
+}
+
+// decimate halves the rate of sample data with a [1 2 1] low-pass filter.
+func decimate(higher []int16, lower []int8) ([]int16, []int8) {
+	at := func(i int) int32 {
+		if i >= len(higher) {
+			i = len(higher) - 1
+		}
+		v := int32(higher[i]) << 8
+		if lower != nil {
+			v |= int32(uint8(lower[i]))
+		}
+		return v
+	}
+
+	n := (len(higher) + 1) / 2
+	outHigher := make([]int16, n)
+	var outLower []int8
+	if lower != nil {
+		outLower = make([]int8, n)
+	}
+	for i := 0; i < n; i++ {
+		prev := at(2 * i)
+		if i > 0 {
+			prev = at(2*i - 1)
+		}
+		v := (prev + 2*at(2*i) + at(2*i+1) + 2) >> 2
+		outHigher[i] = int16(v >> 8)
+		if outLower != nil {
+			outLower[i] = int8(v)
+		}
+	}
+	return outHigher, outLower
+}
+
+// halveSampleRates resamples every sample that stays at or above
+// minFitSampleRate to half its rate.
+func (sf *SoundFont) halveSampleRates() (string, error) {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return "", err
+	}
+
+	halved := map[int]bool{}
+	pool := &samplePool{}
+	for i, s := range l.Samples {
+		higher, lower := sf.SampleData(s)
+		if !s.isROM() && s.SampleRate/2 >= minFitSampleRate && len(higher) > 0 {
+			higher, lower = decimate(higher, lower)
+			rel := func(v uint32) uint32 {
+				if v < s.Start {
+					return 0
+				}
+				return (v - s.Start) / 2
+			}
+			s.Startloop, s.Endloop, s.Start = rel(s.Startloop), rel(s.Endloop), 0
+			s.SampleRate /= 2
+			halved[i] = true
+		}
+		l.Samples[i] = pool.add(s, higher, lower)
+	}
+	if len(halved) == 0 {
+		return "", nil
+	}
+
+	for _, inst := range l.Instruments {
+		all := true
+		for j := range inst.Zones {
+			if id, ok := inst.Zones[j].terminal(Gen_SampleID); ok {
+				if halved[int(uint16(id))] {
+					halveOffsets(&inst.Zones[j])
+				} else {
+					all = false
+				}
+			}
+		}
+		// a global zone's offsets apply to every sample of the instrument
+		if len(inst.Zones) > 0 && all {
+			if _, ok := inst.Zones[0].terminal(Gen_SampleID); !ok {
+				halveOffsets(&inst.Zones[0])
+			}
+		}
+	}
+
+	if sf.Hydra, err = l.Pack(); err != nil {
+		return "", err
+	}
+	sf.Samples = pool.samples()
+	return fmt.Sprintf("halved the sample rate of %d samples", len(halved)), nil
+}
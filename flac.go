@@ -0,0 +1,262 @@
+package sf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+)
+
+// FLACEncoder writes 16-bit FLAC files using fixed linear predictors and Rice
+// coded residuals. It compresses less than the reference encoder but is
+// lossless and decodes anywhere.
+type FLACEncoder struct {
+	// BlockSize is the number of frames per FLAC frame. Zero means 4096.
+	BlockSize int
+}
+
+// bitWriter packs bits most significant first.
+type bitWriter struct {
+	buf   bytes.Buffer
+	acc   uint64
+	nbits uint
+}
+
+func (b *bitWriter) write(v uint64, n uint) {
+	for n > 0 {
+		take := n
+		if take > 32 {
+			take = 32
+		}
+		n -= take
+		b.acc = b.acc<<take | (v>>n)&(1<<take-1)
+		b.nbits += take
+		for b.nbits >= 8 {
+			b.nbits -= 8
+			b.buf.WriteByte(byte(b.acc >> b.nbits))
+		}
+	}
+}
+
+func (b *bitWriter) writeSigned(v int64, n uint) {
+	b.write(uint64(v)&(1<<n-1), n)
+}
+
+// align pads with zero bits to a byte boundary.
+func (b *bitWriter) align() {
+	if b.nbits > 0 {
+		b.write(0, 8-b.nbits)
+	}
+}
+
+func crc8(data []byte) byte {
+	var crc byte
+	for _, d := range data {
+		crc ^= d
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, d := range data {
+		crc ^= uint16(d) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// utf8Number encodes a frame number the way FLAC frame headers do.
+func utf8Number(n uint64) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var out []byte
+	bytesNeeded := 2
+	for n >= 1<<(5*bytesNeeded+1) {
+		bytesNeeded++
+	}
+	for i := bytesNeeded - 1; i > 0; i-- {
+		out = append([]byte{0x80 | byte(n&0x3f)}, out...)
+		n >>= 6
+	}
+	lead := byte(0xff<<(8-bytesNeeded)) | byte(n)
+	return append([]byte{lead}, out...)
+}
+
+// fixedResidual returns the residual of the fixed predictor of the given
+// order, skipping the first order warm-up samples.
+func fixedResidual(s []int64, order int) []int64 {
+	res := make([]int64, 0, len(s)-order)
+	for i := order; i < len(s); i++ {
+		var p int64
+		switch order {
+		case 1:
+			p = s[i-1]
+		case 2:
+			p = 2*s[i-1] - s[i-2]
+		case 3:
+			p = 3*s[i-1] - 3*s[i-2] + s[i-3]
+		case 4:
+			p = 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+		}
+		res = append(res, s[i]-p)
+	}
+	return res
+}
+
+// riceCost returns the best Rice parameter for the residual and the number of
+// bits it codes to.
+func riceCost(res []int64) (param uint, bits uint64) {
+	bits = ^uint64(0)
+	for k := uint(0); k < 15; k++ {
+		var total uint64
+		for _, r := range res {
+			u := uint64(r<<1) ^ uint64(r>>63)
+			total += u>>k + 1 + uint64(k)
+		}
+		if total < bits {
+			param, bits = k, total
+		}
+	}
+	return param, bits
+}
+
+// writeSubframe writes the smallest of a verbatim or fixed predictor subframe.
+func writeSubframe(b *bitWriter, s []int64, bps uint) {
+	bestOrder, bestParam, bestBits := -1, uint(0), uint64(len(s))*uint64(bps)
+	for order := 0; order <= 4 && order < len(s); order++ {
+		param, bits := riceCost(fixedResidual(s, order))
+		bits += uint64(order)*uint64(bps) + 2 + 4 + 4
+		if bits < bestBits {
+			bestOrder, bestParam, bestBits = order, param, bits
+		}
+	}
+
+	if bestOrder < 0 {
+		// verbatim
+		b.write(0x02, 8)
+		for _, v := range s {
+			b.writeSigned(v, bps)
+		}
+		return
+	}
+
+	b.write(uint64(0x08|bestOrder)<<1, 8)
+	for _, v := range s[:bestOrder] {
+		b.writeSigned(v, bps)
+	}
+	// Rice coding, partition order 0
+	b.write(0, 2)
+	b.write(0, 4)
+	b.write(uint64(bestParam), 4)
+	for _, r := range fixedResidual(s, bestOrder) {
+		u := uint64(r<<1) ^ uint64(r>>63)
+		for q := u >> bestParam; q > 0; q-- {
+			b.write(0, 1)
+		}
+		b.write(1, 1)
+		b.write(u&(1<<bestParam-1), bestParam)
+	}
+}
+
+func (e FLACEncoder) Encode(w io.Writer, channels [][]float32, sampleRate int) error {
+	frames, err := checkChannels(channels, sampleRate)
+	if err != nil {
+		return err
+	}
+	if len(channels) > 8 {
+		return fmt.Errorf("FLAC supports at most 8 channels, got %d", len(channels))
+	}
+	if sampleRate >= 1<<20 {
+		return fmt.Errorf("sample rate %d is too high for FLAC", sampleRate)
+	}
+	blockSize := e.BlockSize
+	if blockSize == 0 {
+		blockSize = 4096
+	}
+	if blockSize < 16 || blockSize > 65535 {
+		return fmt.Errorf("invalid FLAC block size %d", blockSize)
+	}
+	const bps = 16
+
+	// the STREAMINFO block carries an MD5 of the interleaved little endian
+	// samples
+	sum := md5.New()
+	samples := make([][]int64, len(channels))
+	for c, ch := range channels {
+		samples[c] = make([]int64, frames)
+		for i, v := range ch {
+			samples[c][i] = int64(quantize(v, bps))
+		}
+	}
+	for i := 0; i < frames; i++ {
+		for c := range samples {
+			s := samples[c][i]
+			sum.Write([]byte{byte(s), byte(s >> 8)})
+		}
+	}
+
+	var head bitWriter
+	head.buf.WriteString("fLaC")
+	head.write(1, 1) // last metadata block
+	head.write(0, 7) // STREAMINFO
+	head.write(34, 24)
+	head.write(uint64(blockSize), 16)
+	head.write(uint64(blockSize), 16)
+	head.write(0, 24) // minimum frame size unknown
+	head.write(0, 24) // maximum frame size unknown
+	head.write(uint64(sampleRate), 20)
+	head.write(uint64(len(channels)-1), 3)
+	head.write(bps-1, 5)
+	head.write(uint64(frames), 36)
+	head.buf.Write(sum.Sum(nil))
+	if _, err := w.Write(head.buf.Bytes()); err != nil {
+		return err
+	}
+
+	for frame, start := uint64(0), 0; start < frames; frame, start = frame+1, start+blockSize {
+		end := start + blockSize
+		if end > frames {
+			end = frames
+		}
+		n := end - start
+
+		var b bitWriter
+		b.write(0xfff8, 16)
+		b.write(0x7, 4) // block size in 16 bits after the header
+		b.write(0x0, 4) // sample rate from STREAMINFO
+		b.write(uint64(len(channels)-1), 4)
+		b.write(0x4, 3) // 16 bits per sample
+		b.write(0, 1)
+		b.buf.Write(utf8Number(frame))
+		b.write(uint64(n-1), 16)
+		b.buf.WriteByte(crc8(b.buf.Bytes()))
+
+		for c := range samples {
+			writeSubframe(&b, samples[c][start:end], bps)
+		}
+		b.align()
+		crc := crc16(b.buf.Bytes())
+		b.write(uint64(crc), 16)
+
+		if _, err := w.Write(b.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
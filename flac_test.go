@@ -0,0 +1,278 @@
+package sf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bitReader reads bits most significant first, the inverse of bitWriter.
+type bitReader struct {
+	data []byte
+	pos  uint // in bits
+}
+
+func (r *bitReader) read(n uint) (uint64, error) {
+	var v uint64
+	for ; n > 0; n-- {
+		if r.pos >= uint(len(r.data))*8 {
+			return 0, fmt.Errorf("read past the end")
+		}
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+func (r *bitReader) readSigned(n uint) (int64, error) {
+	v, err := r.read(n)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 && v&(1<<(n-1)) != 0 {
+		return int64(v) - 1<<n, nil
+	}
+	return int64(v), nil
+}
+
+func (r *bitReader) readUnary() (uint64, error) {
+	var q uint64
+	for {
+		bit, err := r.read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			return q, nil
+		}
+		q++
+	}
+}
+
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// decodeFLAC is a minimal decoder for the subset of FLAC FLACEncoder
+// writes: a STREAMINFO block, then frames of constant, verbatim and fixed
+// predictor subframes with Rice coded residuals. It checks both CRCs and
+// the MD5 of the decoded audio.
+func decodeFLAC(data []byte) (channels [][]int64, sampleRate int, err error) {
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return nil, 0, fmt.Errorf("missing fLaC marker")
+	}
+	r := &bitReader{data: data, pos: 32}
+
+	last, _ := r.read(1)
+	kind, _ := r.read(7)
+	length, _ := r.read(24)
+	if last != 1 || kind != 0 || length != 34 {
+		return nil, 0, fmt.Errorf("expected a lone STREAMINFO block, got type %d length %d", kind, length)
+	}
+	r.read(16 + 16 + 24 + 24)
+	rate, _ := r.read(20)
+	nch, _ := r.read(3)
+	bpsm1, _ := r.read(5)
+	total, _ := r.read(36)
+	sum := data[r.pos/8 : r.pos/8+16]
+	r.pos += 128
+	bps := uint(bpsm1 + 1)
+	channels = make([][]int64, nch+1)
+
+	for frame := uint64(0); r.pos < uint(len(data))*8; frame++ {
+		start := r.pos / 8
+		if sync, _ := r.read(16); sync != 0xfff8 {
+			return nil, 0, fmt.Errorf("frame %d: bad sync %#x", frame, sync)
+		}
+		sizeCode, _ := r.read(4)
+		rateCode, _ := r.read(4)
+		chCode, _ := r.read(4)
+		bpsCode, _ := r.read(3)
+		r.read(1)
+		if sizeCode != 7 || rateCode != 0 || chCode != nch || bpsCode != 4 {
+			return nil, 0, fmt.Errorf("frame %d: unexpected header codes", frame)
+		}
+
+		// UTF-8 style frame number
+		lead, _ := r.read(8)
+		number, extra := lead, 0
+		for mask := uint64(0x80); lead&mask != 0; mask >>= 1 {
+			extra++
+		}
+		if extra > 0 {
+			number = lead & (0xff >> (extra + 1))
+			extra--
+		}
+		for ; extra > 0; extra-- {
+			b, _ := r.read(8)
+			number = number<<6 | b&0x3f
+		}
+		if number != frame {
+			return nil, 0, fmt.Errorf("frame %d numbered %d", frame, number)
+		}
+		nm1, _ := r.read(16)
+		n := int(nm1 + 1)
+		if crc, _ := r.read(8); byte(crc) != crc8(data[start:r.pos/8-1]) {
+			return nil, 0, fmt.Errorf("frame %d: header CRC mismatch", frame)
+		}
+
+		for c := range channels {
+			s, err := decodeSubframe(r, n, bps)
+			if err != nil {
+				return nil, 0, fmt.Errorf("frame %d channel %d: %v", frame, c, err)
+			}
+			channels[c] = append(channels[c], s...)
+		}
+		r.align()
+		end := r.pos / 8
+		if crc, _ := r.read(16); uint16(crc) != crc16(data[start:end]) {
+			return nil, 0, fmt.Errorf("frame %d: frame CRC mismatch", frame)
+		}
+	}
+
+	if uint64(len(channels[0])) != total {
+		return nil, 0, fmt.Errorf("decoded %d frames, STREAMINFO says %d", len(channels[0]), total)
+	}
+	h := md5.New()
+	for i := range channels[0] {
+		for c := range channels {
+			h.Write([]byte{byte(channels[c][i]), byte(channels[c][i] >> 8)})
+		}
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		return nil, 0, fmt.Errorf("MD5 mismatch")
+	}
+	return channels, int(rate), nil
+}
+
+func decodeSubframe(r *bitReader, n int, bps uint) ([]int64, error) {
+	header, err := r.read(8)
+	if err != nil {
+		return nil, err
+	}
+	if header&0x81 != 0 {
+		return nil, fmt.Errorf("padding or wasted bits set in subframe header %#x", header)
+	}
+	kind := header >> 1
+	s := make([]int64, 0, n)
+	switch {
+	case kind == 0:
+		v, _ := r.readSigned(bps)
+		for i := 0; i < n; i++ {
+			s = append(s, v)
+		}
+		return s, nil
+	case kind == 1:
+		for i := 0; i < n; i++ {
+			v, err := r.readSigned(bps)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		return s, nil
+	case kind&0x38 == 0x08 && kind&7 <= 4:
+		// FIXED, 001ooo
+	default:
+		return nil, fmt.Errorf("unsupported or reserved subframe type %06b", kind)
+	}
+
+	order := int(kind & 7)
+	for i := 0; i < order; i++ {
+		v, _ := r.readSigned(bps)
+		s = append(s, v)
+	}
+	method, _ := r.read(2)
+	if method != 0 {
+		return nil, fmt.Errorf("unsupported residual coding method %d", method)
+	}
+	partOrder, _ := r.read(4)
+	parts := 1 << partOrder
+	for p := 0; p < parts; p++ {
+		count := n >> partOrder
+		if p == 0 {
+			count -= order
+		}
+		param, _ := r.read(4)
+		if param == 15 {
+			return nil, fmt.Errorf("escaped partitions are not supported")
+		}
+		for i := 0; i < count; i++ {
+			q, err := r.readUnary()
+			if err != nil {
+				return nil, err
+			}
+			low, _ := r.read(uint(param))
+			u := q<<param | low
+			res := int64(u>>1) ^ -int64(u&1)
+
+			var pred int64
+			k := len(s)
+			switch order {
+			case 1:
+				pred = s[k-1]
+			case 2:
+				pred = 2*s[k-1] - s[k-2]
+			case 3:
+				pred = 3*s[k-1] - 3*s[k-2] + s[k-3]
+			case 4:
+				pred = 4*s[k-1] - 6*s[k-2] + 4*s[k-3] - s[k-4]
+			}
+			s = append(s, pred+res)
+		}
+	}
+	return s, nil
+}
+
+func TestFLACRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ramp := make([]float32, 5000)
+	sine := make([]float32, 5000)
+	noise := make([]float32, 5000)
+	for i := range ramp {
+		ramp[i] = float32(i)/5000*2 - 1
+		sine[i] = float32(0.8 * math.Sin(float64(i)*0.05))
+		noise[i] = float32(rng.Float64()*2 - 1)
+	}
+
+	tests := []struct {
+		name      string
+		channels  [][]float32
+		blockSize int
+	}{
+		{"ramp", [][]float32{ramp}, 0},
+		{"sine", [][]float32{sine}, 0},
+		{"silence", [][]float32{make([]float32, 1000)}, 0},
+		{"noise", [][]float32{noise}, 0},
+		{"stereo", [][]float32{sine, ramp}, 1024},
+		// more than 128 frames, for multi-byte frame numbers
+		{"small blocks", [][]float32{sine}, 16},
+		{"short", [][]float32{{0.5, -0.5, 0.25}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (FLACEncoder{BlockSize: tt.blockSize}).Encode(&buf, tt.channels, 44100); err != nil {
+				t.Fatal(err)
+			}
+			got, rate, err := decodeFLAC(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rate != 44100 {
+				t.Errorf("sample rate %d, want 44100", rate)
+			}
+			for c, ch := range tt.channels {
+				for i, v := range ch {
+					if want := int64(quantize(v, 16)); got[c][i] != want {
+						t.Fatalf("channel %d sample %d is %d, want %d", c, i, got[c][i], want)
+					}
+				}
+			}
+		})
+	}
+}
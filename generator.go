@@ -0,0 +1,198 @@
+package sf
+
+import "fmt"
+
+// The generator operators defined by the SoundFont 2.04 specification.
+const (
+	Gen_StartAddrsOffset           SFGenerator = 0
+	Gen_EndAddrsOffset             SFGenerator = 1
+	Gen_StartloopAddrsOffset       SFGenerator = 2
+	Gen_EndloopAddrsOffset         SFGenerator = 3
+	Gen_StartAddrsCoarseOffset     SFGenerator = 4
+	Gen_ModLfoToPitch              SFGenerator = 5
+	Gen_VibLfoToPitch              SFGenerator = 6
+	Gen_ModEnvToPitch              SFGenerator = 7
+	Gen_InitialFilterFc            SFGenerator = 8
+	Gen_InitialFilterQ             SFGenerator = 9
+	Gen_ModLfoToFilterFc           SFGenerator = 10
+	Gen_ModEnvToFilterFc           SFGenerator = 11
+	Gen_EndAddrsCoarseOffset       SFGenerator = 12
+	Gen_ModLfoToVolume             SFGenerator = 13
+	Gen_Unused1                    SFGenerator = 14
+	Gen_ChorusEffectsSend          SFGenerator = 15
+	Gen_ReverbEffectsSend          SFGenerator = 16
+	Gen_Pan                        SFGenerator = 17
+	Gen_Unused2                    SFGenerator = 18
+	Gen_Unused3                    SFGenerator = 19
+	Gen_Unused4                    SFGenerator = 20
+	Gen_DelayModLFO                SFGenerator = 21
+	Gen_FreqModLFO                 SFGenerator = 22
+	Gen_DelayVibLFO                SFGenerator = 23
+	Gen_FreqVibLFO                 SFGenerator = 24
+	Gen_DelayModEnv                SFGenerator = 25
+	Gen_AttackModEnv               SFGenerator = 26
+	Gen_HoldModEnv                 SFGenerator = 27
+	Gen_DecayModEnv                SFGenerator = 28
+	Gen_SustainModEnv              SFGenerator = 29
+	Gen_ReleaseModEnv              SFGenerator = 30
+	Gen_KeynumToModEnvHold         SFGenerator = 31
+	Gen_KeynumToModEnvDecay        SFGenerator = 32
+	Gen_DelayVolEnv                SFGenerator = 33
+	Gen_AttackVolEnv               SFGenerator = 34
+	Gen_HoldVolEnv                 SFGenerator = 35
+	Gen_DecayVolEnv                SFGenerator = 36
+	Gen_SustainVolEnv              SFGenerator = 37
+	Gen_ReleaseVolEnv              SFGenerator = 38
+	Gen_KeynumToVolEnvHold         SFGenerator = 39
+	Gen_KeynumToVolEnvDecay        SFGenerator = 40
+	Gen_Instrument                 SFGenerator = 41
+	Gen_Reserved1                  SFGenerator = 42
+	Gen_KeyRange                   SFGenerator = 43
+	Gen_VelRange                   SFGenerator = 44
+	Gen_StartloopAddrsCoarseOffset SFGenerator = 45
+	Gen_Keynum                     SFGenerator = 46
+	Gen_Velocity                   SFGenerator = 47
+	Gen_InitialAttenuation         SFGenerator = 48
+	Gen_Reserved2                  SFGenerator = 49
+	Gen_EndloopAddrsCoarseOffset   SFGenerator = 50
+	Gen_CoarseTune                 SFGenerator = 51
+	Gen_FineTune                   SFGenerator = 52
+	Gen_SampleID                   SFGenerator = 53
+	Gen_SampleModes                SFGenerator = 54
+	Gen_Reserved3                  SFGenerator = 55
+	Gen_ScaleTuning                SFGenerator = 56
+	Gen_ExclusiveClass             SFGenerator = 57
+	Gen_OverridingRootKey          SFGenerator = 58
+	Gen_Unused5                    SFGenerator = 59
+	Gen_EndOper                    SFGenerator = 60
+)
+
+// generatorNames are the operator names used by the SoundFont 2.04
+// specification.
+var generatorNames = [Gen_EndOper]string{
+	"startAddrsOffset", "endAddrsOffset", "startloopAddrsOffset", "endloopAddrsOffset",
+	"startAddrsCoarseOffset", "modLfoToPitch", "vibLfoToPitch", "modEnvToPitch",
+	"initialFilterFc", "initialFilterQ", "modLfoToFilterFc", "modEnvToFilterFc",
+	"endAddrsCoarseOffset", "modLfoToVolume", "unused1", "chorusEffectsSend",
+	"reverbEffectsSend", "pan", "unused2", "unused3",
+	"unused4", "delayModLFO", "freqModLFO", "delayVibLFO",
+	"freqVibLFO", "delayModEnv", "attackModEnv", "holdModEnv",
+	"decayModEnv", "sustainModEnv", "releaseModEnv", "keynumToModEnvHold",
+	"keynumToModEnvDecay", "delayVolEnv", "attackVolEnv", "holdVolEnv",
+	"decayVolEnv", "sustainVolEnv", "releaseVolEnv", "keynumToVolEnvHold",
+	"keynumToVolEnvDecay", "instrument", "reserved1", "keyRange",
+	"velRange", "startloopAddrsCoarseOffset", "keynum", "velocity",
+	"initialAttenuation", "reserved2", "endloopAddrsCoarseOffset", "coarseTune",
+	"fineTune", "sampleID", "sampleModes", "reserved3",
+	"scaleTuning", "exclusiveClass", "overridingRootKey", "unused5",
+}
+
+// String returns op's specification name, such as "initialFilterFc", or
+// "gen" and its number for operators outside the specification.
+func (op SFGenerator) String() string {
+	if op < Gen_EndOper {
+		return generatorNames[op]
+	}
+	return fmt.Sprintf("gen%d", uint16(op))
+}
+
+// IsValid reports whether op is a generator the specification defines for
+// use. The unused and reserved operators, endOper and anything past it are
+// not, and the spec has their generators ignored.
+func (op SFGenerator) IsValid() bool {
+	switch op {
+	case Gen_Unused1, Gen_Unused2, Gen_Unused3, Gen_Unused4, Gen_Unused5,
+		Gen_Reserved1, Gen_Reserved2, Gen_Reserved3:
+		return false
+	}
+	return op < Gen_EndOper
+}
+
+// GenAmount is a generator's amount, the raw 16 bits of the genAmountType
+// union. Which reading applies depends on the operator: keyRange and
+// velRange hold a range, instrument and sampleID an index and sampleModes
+// flags, all unsigned, and every other generator a signed value.
+// Generator.Value picks the reading for its operator.
+type GenAmount int16
+
+// AsInt16 reads the amount as a signed SHORT.
+func (a GenAmount) AsInt16() int16 {
+	return int16(a)
+}
+
+// AsUint16 reads the amount as an unsigned WORD.
+func (a GenAmount) AsUint16() uint16 {
+	return uint16(a)
+}
+
+// AsRange reads the amount as a range, lo in the low byte and hi in the
+// high byte.
+func (a GenAmount) AsRange() (lo, hi byte) {
+	return rangeBytes(int16(a))
+}
+
+// RangeAmount packs lo and hi into a range amount.
+func RangeAmount(lo, hi byte) GenAmount {
+	return GenAmount(makeRange(lo, hi))
+}
+
+// unsigned reports whether op's amount is an unsigned WORD.
+func (op SFGenerator) unsigned() bool {
+	switch op {
+	case Gen_Instrument, Gen_SampleID, Gen_SampleModes:
+		return true
+	}
+	return false
+}
+
+// Value returns the amount read the way the operator defines it: a [2]byte
+// of lo and hi for keyRange and velRange, a uint16 for the unsigned
+// generators and an int16 for the rest.
+func (g Generator) Value() interface{} {
+	switch {
+	case g.GenOper == Gen_KeyRange || g.GenOper == Gen_VelRange:
+		lo, hi := g.GenAmount.AsRange()
+		return [2]byte{lo, hi}
+	case g.GenOper.unsigned():
+		return g.GenAmount.AsUint16()
+	}
+	return g.GenAmount.AsInt16()
+}
+
+// String formats the generator as its operator name and amount, such as
+// "keyRange 36-60" or "pan -250".
+func (g Generator) String() string {
+	switch v := g.Value().(type) {
+	case [2]byte:
+		return fmt.Sprintf("%s %d-%d", g.GenOper, v[0], v[1])
+	default:
+		return fmt.Sprintf("%s %d", g.GenOper, v)
+	}
+}
+
+// Gen_InitialPitch is the destination of the default pitch wheel modulator. It
+// shares its value with Gen_Unused5 and addresses the voice's pitch directly.
+const Gen_InitialPitch = Gen_Unused5
+
+// PresetLegal reports whether op may appear in a preset zone. Sample
+// addressing, keynum, velocity, sampleModes, exclusiveClass and
+// overridingRootKey only make sense for a particular sample and are
+// instrument-only, as is the sampleID terminal.
+func PresetLegal(op SFGenerator) bool {
+	switch op {
+	case Gen_StartAddrsOffset, Gen_EndAddrsOffset,
+		Gen_StartloopAddrsOffset, Gen_EndloopAddrsOffset,
+		Gen_StartAddrsCoarseOffset, Gen_EndAddrsCoarseOffset,
+		Gen_StartloopAddrsCoarseOffset, Gen_EndloopAddrsCoarseOffset,
+		Gen_Keynum, Gen_Velocity, Gen_SampleModes, Gen_ExclusiveClass,
+		Gen_OverridingRootKey, Gen_SampleID:
+		return false
+	}
+	return true
+}
+
+// InstrumentLegal reports whether op may appear in an instrument zone. Only
+// the instrument terminal is preset-only.
+func InstrumentLegal(op SFGenerator) bool {
+	return op != Gen_Instrument
+}
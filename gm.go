@@ -0,0 +1,174 @@
+package sf
+
+import (
+	"math"
+	"math/rand"
+)
+
+// GMProgramNames are the General MIDI Level 1 instrument names, indexed by
+// program number.
+var GMProgramNames = [128]string{
+	"Acoustic Grand Piano", "Bright Acoustic Piano", "Electric Grand Piano", "Honky-tonk Piano",
+	"Electric Piano 1", "Electric Piano 2", "Harpsichord", "Clavi",
+	"Celesta", "Glockenspiel", "Music Box", "Vibraphone",
+	"Marimba", "Xylophone", "Tubular Bells", "Dulcimer",
+	"Drawbar Organ", "Percussive Organ", "Rock Organ", "Church Organ",
+	"Reed Organ", "Accordion", "Harmonica", "Tango Accordion",
+	"Nylon Guitar", "Steel Guitar", "Jazz Guitar", "Clean Guitar",
+	"Muted Guitar", "Overdriven Guitar", "Distortion Guitar", "Guitar Harmonics",
+	"Acoustic Bass", "Finger Bass", "Pick Bass", "Fretless Bass",
+	"Slap Bass 1", "Slap Bass 2", "Synth Bass 1", "Synth Bass 2",
+	"Violin", "Viola", "Cello", "Contrabass",
+	"Tremolo Strings", "Pizzicato Strings", "Orchestral Harp", "Timpani",
+	"String Ensemble 1", "String Ensemble 2", "Synth Strings 1", "Synth Strings 2",
+	"Choir Aahs", "Voice Oohs", "Synth Voice", "Orchestra Hit",
+	"Trumpet", "Trombone", "Tuba", "Muted Trumpet",
+	"French Horn", "Brass Section", "Synth Brass 1", "Synth Brass 2",
+	"Soprano Sax", "Alto Sax", "Tenor Sax", "Baritone Sax",
+	"Oboe", "English Horn", "Bassoon", "Clarinet",
+	"Piccolo", "Flute", "Recorder", "Pan Flute",
+	"Blown Bottle", "Shakuhachi", "Whistle", "Ocarina",
+	"Lead 1 (square)", "Lead 2 (sawtooth)", "Lead 3 (calliope)", "Lead 4 (chiff)",
+	"Lead 5 (charang)", "Lead 6 (voice)", "Lead 7 (fifths)", "Lead 8 (bass+lead)",
+	"Pad 1 (new age)", "Pad 2 (warm)", "Pad 3 (polysynth)", "Pad 4 (choir)",
+	"Pad 5 (bowed)", "Pad 6 (metallic)", "Pad 7 (halo)", "Pad 8 (sweep)",
+	"FX 1 (rain)", "FX 2 (soundtrack)", "FX 3 (crystal)", "FX 4 (atmosphere)",
+	"FX 5 (brightness)", "FX 6 (goblins)", "FX 7 (echoes)", "FX 8 (sci-fi)",
+	"Sitar", "Banjo", "Shamisen", "Koto",
+	"Kalimba", "Bag pipe", "Fiddle", "Shanai",
+	"Tinkle Bell", "Agogo", "Steel Drums", "Woodblock",
+	"Taiko Drum", "Melodic Tom", "Synth Drum", "Reverse Cymbal",
+	"Guitar Fret Noise", "Breath Noise", "Seashore", "Bird Tweet",
+	"Telephone Ring", "Helicopter", "Applause", "Gunshot",
+}
+
+// GMFamilyNames are the sixteen General MIDI instrument families. Program p
+// belongs to family p/8.
+var GMFamilyNames = [16]string{
+	"Piano", "Chromatic Percussion", "Organ", "Guitar",
+	"Bass", "Strings", "Ensemble", "Brass",
+	"Reed", "Pipe", "Synth Lead", "Synth Pad",
+	"Synth Effects", "Ethnic", "Percussive", "Sound Effects",
+}
+
+// The waveforms of the placeholder bank, also the order of its samples.
+const (
+	waveSine = iota
+	waveTriangle
+	waveSquare
+	waveSaw
+	waveNoise
+)
+
+// gmFamilyVoices describes the placeholder sound of each GM family: its
+// waveform and volume envelope. Sustain is an attenuation in centibels, 1000
+// being silence.
+var gmFamilyVoices = [16]struct {
+	wave                   int
+	attack, decay, release float64
+	sustain                int16
+}{
+	{waveTriangle, 0.001, 3, 0.5, 1000},
+	{waveSine, 0.001, 1.5, 0.5, 1000},
+	{waveSquare, 0.01, 1, 0.1, 0},
+	{waveSaw, 0.001, 2, 0.3, 1000},
+	{waveSaw, 0.001, 1.5, 0.2, 300},
+	{waveSaw, 0.1, 1, 0.5, 0},
+	{waveSaw, 0.2, 1, 0.8, 0},
+	{waveSaw, 0.05, 0.5, 0.2, 100},
+	{waveSquare, 0.03, 1, 0.2, 0},
+	{waveSine, 0.05, 1, 0.3, 0},
+	{waveSquare, 0.005, 1, 0.1, 0},
+	{waveTriangle, 0.5, 1, 1.5, 0},
+	{waveTriangle, 0.3, 2, 1, 400},
+	{waveSaw, 0.005, 1, 0.3, 600},
+	{waveSine, 0.001, 0.5, 0.2, 1000},
+	{waveNoise, 0.01, 1, 0.3, 1000},
+}
+
+// synthWave returns one looped placeholder waveform.
+func synthWave(wave int) []int16 {
+	rng := rand.New(rand.NewSource(1))
+	d := make([]int16, synthLength)
+	for n := range d {
+		phase := float64(n%synthPeriod) / synthPeriod
+		var v float64
+		switch wave {
+		case waveSine:
+			v = math.Sin(2 * math.Pi * phase)
+		case waveTriangle:
+			v = 1 - 4*math.Abs(phase-0.5)
+		case waveSquare:
+			v = 1
+			if phase >= 0.5 {
+				v = -1
+			}
+		case waveSaw:
+			v = 2*phase - 1
+		case waveNoise:
+			v = rng.Float64()*2 - 1
+		}
+		d[n] = int16(v * 12000)
+	}
+	return d
+}
+
+// envelope returns the volume envelope generators of a placeholder voice.
+func envelope(attack, decay, release float64, sustain int16) []Generator {
+	return []Generator{
+		{GenOper: Gen_AttackVolEnv, GenAmount: GenAmount(secondsToTimecents(attack))},
+		{GenOper: Gen_DecayVolEnv, GenAmount: GenAmount(secondsToTimecents(decay))},
+		{GenOper: Gen_SustainVolEnv, GenAmount: GenAmount(sustain)},
+		{GenOper: Gen_ReleaseVolEnv, GenAmount: GenAmount(secondsToTimecents(release))},
+		{GenOper: Gen_SampleModes, GenAmount: GenAmount(SampleMode_Continuous)},
+	}
+}
+
+// GenerateGMBank returns a tiny bank that maps all 128 General MIDI programs in
+// bank 0 and a standard drum kit at bank 128 program 0, using simple
+// synthesized waveforms. It is meant as a fallback when no real bank is
+// available: every program sounds, and each family sounds different, but
+// nothing sounds like the instrument it is named after.
+func GenerateGMBank() *SoundFont {
+	l := &Layout{}
+	pool := &samplePool{}
+
+	for wave, name := range []string{"Sine", "Triangle", "Square", "Saw", "Noise"} {
+		l.Samples = append(l.Samples, pool.add(loopedHeader(name), synthWave(wave), nil))
+	}
+
+	for family, v := range gmFamilyVoices {
+		gens := append(envelope(v.attack, v.decay, v.release, v.sustain),
+			Generator{GenOper: Gen_SampleID, GenAmount: GenAmount(v.wave)})
+		l.Instruments = append(l.Instruments, InstrumentData{
+			Name:  fixedName(GMFamilyNames[family]),
+			Zones: []Zone{{Generators: gens}},
+		})
+	}
+
+	for program, name := range GMProgramNames {
+		l.Presets = append(l.Presets, PresetData{
+			Header: PresetHeader{PresetName: fixedName(name), Preset: uint16(program)},
+			Zones:  []Zone{{Generators: []Generator{{GenOper: Gen_Instrument, GenAmount: GenAmount(program / 8)}}}},
+		})
+	}
+
+	// drums: a pitched down sine for the bass drums, short noise bursts for
+	// everything else
+	kick := append([]Generator{{GenOper: Gen_KeyRange, GenAmount: RangeAmount(35, 36)}},
+		envelope(0.001, 0.3, 0.1, 1000)...)
+	kick = append(kick, Generator{GenOper: Gen_SampleID, GenAmount: waveSine})
+	noise := append([]Generator{{GenOper: Gen_KeyRange, GenAmount: RangeAmount(37, 81)}},
+		envelope(0.001, 0.2, 0.1, 1000)...)
+	noise = append(noise, Generator{GenOper: Gen_SampleID, GenAmount: waveNoise})
+	l.Instruments = append(l.Instruments, InstrumentData{
+		Name:  fixedName("Drums"),
+		Zones: []Zone{{Generators: kick}, {Generators: noise}},
+	})
+	l.Presets = append(l.Presets, PresetData{
+		Header: PresetHeader{PresetName: fixedName("Standard Kit"), Preset: 0, Bank: 128},
+		Zones:  []Zone{{Generators: []Generator{{GenOper: Gen_Instrument, GenAmount: GenAmount(len(l.Instruments) - 1)}}}},
+	})
+
+	return &SoundFont{Info: newTestInfo("GM Placeholder"), Samples: pool.samples(), Hydra: l.mustPack()}
+}
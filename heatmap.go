@@ -0,0 +1,130 @@
+package sf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+// Coverage counts how many layers sound for every key and velocity: entry
+// [key][vel] is the number of regions (for a preset) or zones (for an
+// instrument) that play. Velocity 0 is note-off and is always 0.
+type Coverage [128][128]uint8
+
+func (c *Coverage) add(key, vel int) {
+	if c[key][vel] < 255 {
+		c[key][vel]++
+	}
+}
+
+// PresetCoverage returns the coverage of the preset at index i in Headers.
+func (h *SoundFontHydra) PresetCoverage(i int) (*Coverage, error) {
+	c := &Coverage{}
+	for key := 0; key < 128; key++ {
+		for vel := 1; vel < 128; vel++ {
+			regions, err := h.Regions(i, uint8(key), uint8(vel))
+			if err != nil {
+				return nil, err
+			}
+			for range regions {
+				c.add(key, vel)
+			}
+		}
+	}
+	return c, nil
+}
+
+// zoneRange returns a zone's range generator op, falling back to its global
+// zone's and then to the full range.
+func zoneRange(z Zone, op SFGenerator) int16 {
+	for _, zone := range []*Zone{&z, z.Global} {
+		if zone == nil {
+			continue
+		}
+		for _, g := range zone.Generators {
+			if g.GenOper == op {
+				return g.GenAmount.AsInt16()
+			}
+		}
+	}
+	return GeneratorDefaults[op]
+}
+
+// InstrumentCoverage returns the coverage of the instrument at index i in
+// Instuments.
+func (h *SoundFontHydra) InstrumentCoverage(i int) (*Coverage, error) {
+	zones, err := h.InstrumentZones(i)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{}
+	for _, z := range zones {
+		if _, ok := z.terminal(Gen_SampleID); !ok {
+			continue
+		}
+		keys, vels := zoneRange(z, Gen_KeyRange), zoneRange(z, Gen_VelRange)
+		for key := 0; key < 128; key++ {
+			for vel := 1; vel < 128; vel++ {
+				if inRange(keys, uint8(key)) && inRange(vels, uint8(vel)) {
+					c.add(key, vel)
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// coverageColor colors a cell by its number of layers: white for gaps, then
+// darker blues as layers stack up, and red from five layers on.
+func coverageColor(layers uint8) color.RGBA {
+	switch {
+	case layers == 0:
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	case layers >= 5:
+		return color.RGBA{0xc0, 0x39, 0x2b, 0xff}
+	}
+	shade := 0xd0 - 0x30*layers
+	return color.RGBA{shade / 2, shade, 0xff, 0xff}
+}
+
+// Image draws the coverage with keys along the x axis, low to high, and
+// velocities along the y axis, high at the top. Each cell is cell pixels
+// square.
+func (c *Coverage) Image(cell int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 128*cell, 127*cell))
+	for key := 0; key < 128; key++ {
+		for vel := 1; vel < 128; vel++ {
+			y := (127 - vel) * cell
+			r := image.Rect(key*cell, y, (key+1)*cell, y+cell)
+			draw.Draw(img, r, image.NewUniform(coverageColor(c[key][vel])), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// SVG writes the coverage laid out as in Image. Runs of equal cells along a
+// velocity row are merged to keep the file small, and each carries a title
+// with its keys, velocity and layer count.
+func (c *Coverage) SVG(w io.Writer, cell int) error {
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" shape-rendering="crispEdges">`+"\n", 128*cell, 127*cell); err != nil {
+		return err
+	}
+	for vel := 127; vel >= 1; vel-- {
+		y := (127 - vel) * cell
+		for start := 0; start < 128; {
+			end := start + 1
+			for end < 128 && c[end][vel] == c[start][vel] {
+				end++
+			}
+			col := coverageColor(c[start][vel])
+			fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%02x%02x%02x"><title>keys %d-%d, velocity %d: %d layers</title></rect>`+"\n",
+				start*cell, y, (end-start)*cell, cell, col.R, col.G, col.B, start, end-1, vel, c[start][vel])
+			start = end
+		}
+	}
+	_, err := fmt.Fprint(w, "</svg>\n")
+	return err
+}
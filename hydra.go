@@ -1,6 +1,7 @@
-package main
+package sf
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -63,6 +64,11 @@ type PresetHeader struct {
 	Morphology uint32
 }
 
+// Name returns the preset's name without the zero bytes that terminate it.
+func (p PresetHeader) Name() string {
+	return trimName(p.PresetName)
+}
+
 func (p PresetHeader) String() string {
 	return fmt.Sprintf("PresetHeader{PresetName: %q, Preset: %d, Bank: %d, PresetBagNdx: %d, Library: %d, Genre: %d, Morphology: %d}", p.PresetName, p.Preset, p.Bank, p.PresetBagNdx, p.Library, p.Genre, p.Morphology)
 }
@@ -104,7 +110,8 @@ type Generator struct {
 	// GenAmount is the value to be assigned to the specified generator. Note that this can be of three formats. Certain
 	// generators specify a range of MIDI key numbers of MIDI velocities, with a minimum and maximum value. Other
 	// generators specify an unsigned WORD value. Most generators, however, specify a signed 16 bit SHORT value.
-	GenAmount int16
+	// GenOper decides which, see GenAmount.
+	GenAmount GenAmount
 }
 
 type Instrument struct {
@@ -200,6 +207,10 @@ func (s SampleHeader) String() string {
 }
 
 func ReadSoundFontHydra(r io.Reader) (*SoundFontHydra, error) {
+	return readSoundFontHydra(r, ReadOptions{})
+}
+
+func readSoundFontHydra(r io.Reader, opts ReadOptions) (*SoundFontHydra, error) {
 	sound := &SoundFontHydra{}
 
 	pdtaChunks := make(map[[4]byte]bool)
@@ -226,11 +237,10 @@ func ReadSoundFontHydra(r io.Reader) (*SoundFontHydra, error) {
 		_, ok := pdtaChunks[chunk.id]
 		if !ok {
 			// skip unknown chunks
-			fmt.Println("unknown chunk", string(chunk.id[:]))
+			opts.warn("skipped unknown pdta chunk %q", chunk.id[:])
 			continue
 		}
 		pdtaChunks[chunk.id] = true
-		fmt.Println("found chunk", string(chunk.id[:]))
 
 		// make sense of the chunk
 		switch chunk.id {
@@ -370,3 +380,18 @@ func ReadSoundFontHydra(r io.Reader) (*SoundFontHydra, error) {
 
 	return sound, nil
 }
+
+// trimName returns a name field as a string, dropping the zero valued bytes
+// that terminate it.
+func trimName(b [20]byte) string {
+	if i := bytes.IndexByte(b[:], 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b[:])
+}
+
+// fixedName builds a name field from s, truncated to 20 bytes.
+func fixedName(s string) (b [20]byte) {
+	copy(b[:], s)
+	return b
+}
@@ -0,0 +1,267 @@
+package sf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// importer copies instruments and samples from one SoundFont into another's
+// layout, remembering what it already copied.
+type importer struct {
+	dst, src *SoundFont
+	dl, sl   *Layout
+	pool     *samplePool
+
+	// sigs maps the content signature of every sample in dl to its index,
+	// so a sample already there is reused rather than copied again
+	sigs map[string]int
+
+	// samples and instruments map src indices to dl indices
+	samples, instruments map[int]int
+}
+
+func newImporter(dst, src *SoundFont) (*importer, error) {
+	dl, err := dst.Hydra.Unpack()
+	if err != nil {
+		return nil, err
+	}
+	sl, err := src.Hydra.Unpack()
+	if err != nil {
+		return nil, err
+	}
+
+	// new samples go after dst's data, which keeps its addresses
+	pool := &samplePool{}
+	if dst.Samples != nil {
+		pool.higher = dst.Samples.SamplesHigher
+		if len(dst.Samples.SamplesLower) == len(pool.higher) && len(pool.higher) > 0 {
+			pool.lower = dst.Samples.SamplesLower
+			pool.wide = true
+		}
+	}
+
+	im := &importer{
+		dst: dst, src: src, dl: dl, sl: sl, pool: pool,
+		sigs:        map[string]int{},
+		samples:     map[int]int{},
+		instruments: map[int]int{},
+	}
+	for i, s := range dl.Samples {
+		sig := dst.sampleContentSig(s)
+		if _, ok := im.sigs[sig]; !ok {
+			im.sigs[sig] = i
+		}
+	}
+	return im, nil
+}
+
+// sample returns the dst index of src sample i, copying it, and the samples
+// linked to it, unless dst already holds one with the same content.
+func (im *importer) sample(i int) (int, error) {
+	if j, ok := im.samples[i]; ok {
+		return j, nil
+	}
+	if i < 0 || i >= len(im.sl.Samples) {
+		return 0, fmt.Errorf("sample %d out of range", i)
+	}
+	s := im.sl.Samples[i]
+
+	sig := im.src.sampleContentSig(s)
+	if j, ok := im.sigs[sig]; ok {
+		im.samples[i] = j
+		return j, nil
+	}
+	if s.isROM() && !sameROM(im.dst.Info, im.src.Info) {
+		return 0, fmt.Errorf("sample %q is in a ROM the destination does not use", trimName(s.SampleName))
+	}
+
+	higher, lower := im.src.SampleData(s)
+	j := len(im.dl.Samples)
+	im.dl.Samples = append(im.dl.Samples, im.pool.add(s, higher, lower))
+	im.samples[i] = j
+	im.sigs[sig] = j
+
+	if s.SampleType&^0x8000 != SampleType_Mono {
+		link, err := im.sample(int(s.SampleLink))
+		if err != nil {
+			return 0, err
+		}
+		im.dl.Samples[j].SampleLink = uint16(link)
+	}
+	return j, nil
+}
+
+// sameROM reports whether two banks refer to the same wavetable ROM.
+func sameROM(a, b *SoundFontInfo) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return strings.TrimRight(a.ROM, "\x00") == strings.TrimRight(b.ROM, "\x00") && a.ROMVer == b.ROMVer
+}
+
+// instrument returns the dst index of src instrument i, copying it and the
+// samples it plays the first time.
+func (im *importer) instrument(i int) (int, error) {
+	if j, ok := im.instruments[i]; ok {
+		return j, nil
+	}
+	if i < 0 || i >= len(im.sl.Instruments) {
+		return 0, fmt.Errorf("instrument %d out of range", i)
+	}
+
+	inst := InstrumentData{Name: im.sl.Instruments[i].Name}
+	for _, z := range im.sl.Instruments[i].Zones {
+		z = copyZone(z)
+		for k, g := range z.Generators {
+			if g.GenOper == Gen_SampleID {
+				j, err := im.sample(int(uint16(g.GenAmount)))
+				if err != nil {
+					return 0, err
+				}
+				z.Generators[k].GenAmount = GenAmount(j)
+			}
+		}
+		inst.Zones = append(inst.Zones, z)
+	}
+
+	j := len(im.dl.Instruments)
+	im.dl.Instruments = append(im.dl.Instruments, inst)
+	im.instruments[i] = j
+	return j, nil
+}
+
+// finish stores the grown layout and sample data in dst. dst is left as it
+// was when the layout does not fit.
+func (im *importer) finish() error {
+	hydra, err := im.dl.Pack()
+	if err != nil {
+		return err
+	}
+	im.dst.Hydra = hydra
+	im.dst.Samples = im.pool.samples()
+	return nil
+}
+
+// ImportInstrument copies the instrument called name from src into dst,
+// with the samples it plays and those linked to them, and returns its index
+// in dst. Copied sample data is appended after dst's, with the headers
+// rebased onto it. A sample whose data and parameters match one dst already
+// holds is not copied again; the instrument plays dst's instead.
+func ImportInstrument(dst, src *SoundFont, name string) (int, error) {
+	im, err := newImporter(dst, src)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, inst := range im.sl.Instruments {
+		if trimName(inst.Name) != name {
+			continue
+		}
+		j, err := im.instrument(i)
+		if err != nil {
+			return 0, err
+		}
+		if err := im.finish(); err != nil {
+			return 0, err
+		}
+		return j, nil
+	}
+	return 0, fmt.Errorf("no instrument named %q", name)
+}
+
+// ImportPolicy is what ImportPreset does when the destination already has a
+// preset at the bank and program being imported.
+type ImportPolicy int
+
+const (
+	// Import_Fail leaves the destination alone and returns an error.
+	Import_Fail ImportPolicy = iota
+	// Import_Replace puts the imported preset in the existing one's place.
+	// The instruments and samples only the old preset used stay in the bank.
+	Import_Replace
+	// Import_NextBank moves the imported preset to the first bank above
+	// where its program is free, skipping the percussion bank 128. A
+	// percussion kit stays in its bank and moves to the next free program.
+	Import_NextBank
+)
+
+func (p ImportPolicy) String() string {
+	switch p {
+	case Import_Fail:
+		return "fail"
+	case Import_Replace:
+		return "replace"
+	case Import_NextBank:
+		return "next bank"
+	}
+	return "unknown"
+}
+
+// ImportPreset copies the preset at bank and program from src into dst,
+// with every instrument it uses and every sample those play, and returns its
+// index in dst. Samples are shared with dst as ImportInstrument does, and an
+// instrument several zones use is copied once. When dst already has a preset
+// at bank and program, policy decides what happens. Preset names are kept
+// as they are, the spec does not require them to be unique.
+func ImportPreset(dst, src *SoundFont, bank, program uint16, policy ImportPolicy) (int, error) {
+	im, err := newImporter(dst, src)
+	if err != nil {
+		return 0, err
+	}
+
+	from := -1
+	for i, p := range im.sl.Presets {
+		if p.Header.Bank == bank && p.Header.Preset == program {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return 0, fmt.Errorf("no preset at bank %d program %d", bank, program)
+	}
+
+	p := PresetData{Header: im.sl.Presets[from].Header}
+	to := len(im.dl.Presets)
+	for i, q := range im.dl.Presets {
+		if q.Header.Bank != bank || q.Header.Preset != program {
+			continue
+		}
+		switch policy {
+		case Import_Fail:
+			return 0, fmt.Errorf("bank %d program %d is already taken by %q", bank, program, trimName(q.Header.PresetName))
+		case Import_Replace:
+			to = i
+		case Import_NextBank:
+			if p.Header, err = freeSlot(im.dl, p.Header); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("unknown import policy %d", int(policy))
+		}
+		break
+	}
+
+	for _, z := range im.sl.Presets[from].Zones {
+		z = copyZone(z)
+		for k, g := range z.Generators {
+			if g.GenOper == Gen_Instrument {
+				j, err := im.instrument(int(uint16(g.GenAmount)))
+				if err != nil {
+					return 0, err
+				}
+				z.Generators[k].GenAmount = GenAmount(j)
+			}
+		}
+		p.Zones = append(p.Zones, z)
+	}
+
+	if to == len(im.dl.Presets) {
+		im.dl.Presets = append(im.dl.Presets, p)
+	} else {
+		im.dl.Presets[to] = p
+	}
+	if err := im.finish(); err != nil {
+		return 0, err
+	}
+	return to, nil
+}
@@ -0,0 +1,53 @@
+package sf
+
+import "testing"
+
+func TestImportPresetNextBankPercussion(t *testing.T) {
+	// a bank with a kit at 128:0 and a melodic preset at 0:1
+	kits := func() *SoundFont {
+		sf := GenerateSineBank(2)
+		sf.Hydra.Headers[0].Bank = 128
+		return sf
+	}
+	dst, src := kits(), kits()
+
+	i, err := ImportPreset(dst, src, 128, 0, Import_NextBank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := dst.Hydra.Headers[i]
+	if h.Bank != 128 || h.Preset != 1 {
+		t.Errorf("kit imported to bank %d program %d, want bank 128 program 1", h.Bank, h.Preset)
+	}
+
+	i, err = ImportPreset(dst, src, 0, 1, Import_NextBank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := dst.Hydra.Headers[i]; h.Bank != 1 || h.Preset != 1 {
+		t.Errorf("melodic preset imported to bank %d program %d, want bank 1 program 1", h.Bank, h.Preset)
+	}
+}
+
+func TestFreeSlotPercussion(t *testing.T) {
+	l := &Layout{}
+	for program := uint16(0); program < 128; program++ {
+		if program != 5 {
+			l.Presets = append(l.Presets, PresetData{Header: PresetHeader{Bank: 128, Preset: program}})
+		}
+	}
+
+	// wraps around past 127
+	h, err := freeSlot(l, PresetHeader{Bank: 128, Preset: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Bank != 128 || h.Preset != 5 {
+		t.Errorf("got bank %d program %d, want bank 128 program 5", h.Bank, h.Preset)
+	}
+
+	l.Presets = append(l.Presets, PresetData{Header: PresetHeader{Bank: 128, Preset: 5}})
+	if _, err := freeSlot(l, PresetHeader{Bank: 128, Preset: 0}); err == nil {
+		t.Error("found a slot in a full percussion bank")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package sf
 
 import (
 	"fmt"
@@ -92,6 +92,10 @@ func (info SoundFontInfo) String() string {
 
 // ReadSoundFontInfo parses a SoundFont info list.
 func ReadSoundFontInfo(r io.Reader) (*SoundFontInfo, error) {
+	return readSoundFontInfo(r, ReadOptions{})
+}
+
+func readSoundFontInfo(r io.Reader, opts ReadOptions) (*SoundFontInfo, error) {
 	info := &SoundFontInfo{}
 
 	// TODO refactor this out
@@ -132,7 +136,7 @@ func ReadSoundFontInfo(r io.Reader) (*SoundFontInfo, error) {
 		seen, ok := infoChunks[chunk.id]
 		if !ok {
 			// skip unknown chunks
-			fmt.Println("unknown chunk", chunk.id)
+			opts.warn("skipped unknown INFO chunk %q", chunk.id[:])
 			continue
 		}
 		if seen {
@@ -0,0 +1,171 @@
+package sf
+
+import (
+	"fmt"
+	"math"
+)
+
+// PresetData is a preset that owns its zones, detached from the hydra's flat
+// tables. Instrument generators index Layout.Instruments.
+type PresetData struct {
+	// Header is the preset's header. PresetBagNdx is ignored, Pack recomputes it.
+	Header PresetHeader
+	Zones  []Zone
+}
+
+// InstrumentData is an instrument that owns its zones. SampleID generators
+// index Layout.Samples.
+type InstrumentData struct {
+	Name  [20]byte
+	Zones []Zone
+}
+
+// Layout is the hydra unpacked into presets and instruments that own their
+// zones, which is far easier to edit than the bag and generator tables. The
+// terminal records are not part of a Layout, Pack adds them back.
+//
+// Zones of a Layout never have Global set: when a preset or instrument has a
+// global zone it is simply its first zone.
+type Layout struct {
+	Presets     []PresetData
+	Instruments []InstrumentData
+	Samples     []SampleHeader
+}
+
+// copyZone returns a zone that shares no memory with z.
+func copyZone(z Zone) Zone {
+	return Zone{
+		Generators: append([]Generator(nil), z.Generators...),
+		Modulators: append([]Modulator(nil), z.Modulators...),
+	}
+}
+
+// Unpack detaches every preset and instrument from the hydra's tables. The
+// layout shares no memory with the hydra.
+func (h *SoundFontHydra) Unpack() (*Layout, error) {
+	l := &Layout{}
+
+	for i := 0; i+1 < len(h.Headers); i++ {
+		zones, err := h.PresetZones(i)
+		if err != nil {
+			return nil, err
+		}
+		p := PresetData{Header: h.Headers[i]}
+		for _, z := range zones {
+			p.Zones = append(p.Zones, copyZone(z))
+		}
+		l.Presets = append(l.Presets, p)
+	}
+
+	for i := 0; i+1 < len(h.Instuments); i++ {
+		zones, err := h.InstrumentZones(i)
+		if err != nil {
+			return nil, err
+		}
+		inst := InstrumentData{Name: h.Instuments[i].Name}
+		for _, z := range zones {
+			inst.Zones = append(inst.Zones, copyZone(z))
+		}
+		l.Instruments = append(l.Instruments, inst)
+	}
+
+	if len(h.Samples) > 0 {
+		l.Samples = append([]SampleHeader(nil), h.Samples[:len(h.Samples)-1]...)
+	}
+
+	return l, nil
+}
+
+// Pack builds the hydra's flat tables from the layout, including the terminal
+// EOP, EOI and EOS records and the terminal bag, generator and modulator
+// records. The tables are indexed with 16 bits, so a layout with more zones,
+// generators or modulators than that can address is an error.
+func (l *Layout) Pack() (*SoundFontHydra, error) {
+	if err := l.checkIndices(); err != nil {
+		return nil, err
+	}
+	h := &SoundFontHydra{}
+
+	for _, p := range l.Presets {
+		header := p.Header
+		header.PresetBagNdx = uint16(len(h.PBag))
+		h.Headers = append(h.Headers, header)
+
+		for _, z := range p.Zones {
+			h.PBag = append(h.PBag, struct{ GenIndex, ModIndex uint16 }{
+				uint16(len(h.PresetGenerators)), uint16(len(h.PresetModulators)),
+			})
+			h.PresetGenerators = append(h.PresetGenerators, z.Generators...)
+			h.PresetModulators = append(h.PresetModulators, z.Modulators...)
+		}
+	}
+	h.Headers = append(h.Headers, PresetHeader{PresetName: fixedName("EOP"), PresetBagNdx: uint16(len(h.PBag))})
+	h.PBag = append(h.PBag, struct{ GenIndex, ModIndex uint16 }{
+		uint16(len(h.PresetGenerators)), uint16(len(h.PresetModulators)),
+	})
+	h.PresetGenerators = append(h.PresetGenerators, Generator{})
+	h.PresetModulators = append(h.PresetModulators, Modulator{})
+
+	for _, inst := range l.Instruments {
+		h.Instuments = append(h.Instuments, Instrument{Name: inst.Name, InstBagNdx: uint16(len(h.IBag))})
+
+		for _, z := range inst.Zones {
+			h.IBag = append(h.IBag, struct{ InstGenIndex, InstModIndex uint16 }{
+				uint16(len(h.InstrumentGenerators)), uint16(len(h.InstrumentModulators)),
+			})
+			h.InstrumentGenerators = append(h.InstrumentGenerators, z.Generators...)
+			h.InstrumentModulators = append(h.InstrumentModulators, z.Modulators...)
+		}
+	}
+	h.Instuments = append(h.Instuments, Instrument{Name: fixedName("EOI"), InstBagNdx: uint16(len(h.IBag))})
+	h.IBag = append(h.IBag, struct{ InstGenIndex, InstModIndex uint16 }{
+		uint16(len(h.InstrumentGenerators)), uint16(len(h.InstrumentModulators)),
+	})
+	h.InstrumentGenerators = append(h.InstrumentGenerators, Generator{})
+	h.InstrumentModulators = append(h.InstrumentModulators, Modulator{})
+
+	h.Samples = append(append(h.Samples, l.Samples...), SampleHeader{SampleName: fixedName("EOS")})
+
+	return h, nil
+}
+
+// checkIndices checks that every bag, generator and modulator index Pack
+// writes, the terminal records' included, fits in 16 bits.
+func (l *Layout) checkIndices() error {
+	var pbags, pgens, pmods, ibags, igens, imods int
+	for _, p := range l.Presets {
+		pbags += len(p.Zones)
+		for _, z := range p.Zones {
+			pgens += len(z.Generators)
+			pmods += len(z.Modulators)
+		}
+	}
+	for _, inst := range l.Instruments {
+		ibags += len(inst.Zones)
+		for _, z := range inst.Zones {
+			igens += len(z.Generators)
+			imods += len(z.Modulators)
+		}
+	}
+	for _, c := range []struct {
+		n    int
+		what string
+	}{
+		{pbags, "preset zones"}, {pgens, "preset generators"}, {pmods, "preset modulators"},
+		{ibags, "instrument zones"}, {igens, "instrument generators"}, {imods, "instrument modulators"},
+	} {
+		if c.n > math.MaxUint16 {
+			return fmt.Errorf("%d %s do not fit the 16-bit indices of the SoundFont format", c.n, c.what)
+		}
+	}
+	return nil
+}
+
+// mustPack is Pack for the generated banks, whose layouts are known to fit.
+func (l *Layout) mustPack() *SoundFontHydra {
+	h, err := l.Pack()
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,44 @@
+package sf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPackIndexLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		fill  func(l *Layout, n int)
+		error string
+	}{
+		{"preset generators", func(l *Layout, n int) {
+			l.Presets[0].Zones[0].Generators = make([]Generator, n)
+		}, "preset generators"},
+		{"preset modulators", func(l *Layout, n int) {
+			l.Presets[0].Zones[0].Modulators = make([]Modulator, n)
+		}, "preset modulators"},
+		{"instrument zones", func(l *Layout, n int) {
+			l.Instruments[0].Zones = make([]Zone, n)
+		}, "instrument zones"},
+		{"instrument generators", func(l *Layout, n int) {
+			l.Instruments[0].Zones[0].Generators = make([]Generator, n)
+		}, "instrument generators"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := GenerateSineBank(1).Hydra.Unpack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the terminal record's index is the table's length
+			tt.fill(l, 65535)
+			if _, err := l.Pack(); err != nil {
+				t.Fatalf("a table indexed up to 65535: %v", err)
+			}
+			tt.fill(l, 65536)
+			if _, err := l.Pack(); err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("got error %v, want one about %s", err, tt.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package sf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// MaxLoadSize is the largest file LoadFile and LoadAll will parse, 0 for no
+// limit. It keeps a scan of a folder from stalling on a stray multi-gigabyte
+// file.
+var MaxLoadSize int64 = 2 << 30
+
+// LoadFile reads the SoundFont at path. Errors name the file.
+func LoadFile(path string) (*SoundFont, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if MaxLoadSize > 0 {
+		st, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if st.Size() > MaxLoadSize {
+			return nil, fmt.Errorf("%s: %d bytes is over the %d byte limit", path, st.Size(), MaxLoadSize)
+		}
+	}
+
+	sf, err := ReadSoundFont(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sf, nil
+}
+
+// LoadAll reads the SoundFonts at paths using up to workers goroutines, or
+// one per CPU when workers is not positive. The results line up with paths: a
+// file that failed has a nil SoundFont and its error at the same index, and
+// every other entry of the error slice is nil.
+func LoadAll(paths []string, workers int) ([]*SoundFont, []error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	fonts := make([]*SoundFont, len(paths))
+	errs := make([]error, len(paths))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fonts[i], errs[i] = LoadFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return fonts, errs
+}
@@ -0,0 +1,119 @@
+package sf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Localization holds translated display names for a bank and its presets,
+// keyed by BCP 47 language tag such as "de" or "pt-BR". It lives in a JSON
+// sidecar file next to the bank so the 20-byte ASCII names inside the bank
+// stay as they are.
+type Localization struct {
+	// Bank maps languages to the bank's display name.
+	Bank map[string]string `json:"bank,omitempty"`
+
+	// Presets maps "bank:program" to the preset's names by language.
+	Presets map[string]map[string]string `json:"presets,omitempty"`
+}
+
+// LocalizationPath returns the sidecar path for a bank: bank.sf2 becomes
+// bank.names.json.
+func LocalizationPath(bankPath string) string {
+	return strings.TrimSuffix(bankPath, filepath.Ext(bankPath)) + ".names.json"
+}
+
+// LoadLocalization reads a sidecar file.
+func LoadLocalization(r io.Reader) (*Localization, error) {
+	l := &Localization{}
+	if err := json.NewDecoder(r).Decode(l); err != nil {
+		return nil, fmt.Errorf("localization: %w", err)
+	}
+	return l, nil
+}
+
+// Save writes the sidecar file.
+func (l *Localization) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+func presetKey(p PresetHeader) string {
+	return fmt.Sprintf("%d:%d", p.Bank, p.Preset)
+}
+
+// lookup finds the name for lang, falling back to less specific tags:
+// "pt-BR" tries "pt-BR" then "pt".
+func lookup(names map[string]string, lang string) (string, bool) {
+	for lang != "" {
+		if name, ok := names[lang]; ok && name != "" {
+			return name, true
+		}
+		i := strings.LastIndexAny(lang, "-_")
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return "", false
+}
+
+// PresetName returns the preset's name in lang, falling back to less specific
+// tags and then to the name stored in the bank. l may be nil.
+func (l *Localization) PresetName(p PresetHeader, lang string) string {
+	if l != nil {
+		if name, ok := lookup(l.Presets[presetKey(p)], lang); ok {
+			return name
+		}
+	}
+	return trimName(p.PresetName)
+}
+
+// BankName returns the bank's name in lang, falling back like PresetName to
+// the INAM field. l and info may be nil.
+func (l *Localization) BankName(info *SoundFontInfo, lang string) string {
+	if l != nil {
+		if name, ok := lookup(l.Bank, lang); ok {
+			return name
+		}
+	}
+	if info == nil {
+		return ""
+	}
+	return info.Name
+}
+
+// SetPresetName sets the preset's name in lang. An empty name removes it.
+func (l *Localization) SetPresetName(p PresetHeader, lang, name string) {
+	k := presetKey(p)
+	if name == "" {
+		delete(l.Presets[k], lang)
+		if len(l.Presets[k]) == 0 {
+			delete(l.Presets, k)
+		}
+		return
+	}
+	if l.Presets == nil {
+		l.Presets = map[string]map[string]string{}
+	}
+	if l.Presets[k] == nil {
+		l.Presets[k] = map[string]string{}
+	}
+	l.Presets[k][lang] = name
+}
+
+// SetBankName sets the bank's name in lang. An empty name removes it.
+func (l *Localization) SetBankName(lang, name string) {
+	if name == "" {
+		delete(l.Bank, lang)
+		return
+	}
+	if l.Bank == nil {
+		l.Bank = map[string]string{}
+	}
+	l.Bank[lang] = name
+}
@@ -0,0 +1,169 @@
+package sf
+
+import "math"
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the two stage K-weighting filter of ITU-R BS.1770 for
+// sampleRate: a high shelf modelling the head, then a high pass.
+func kWeighting(sampleRate int) (shelf, highpass biquad) {
+	rate := float64(sampleRate)
+
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + k/q + k*k
+	highpass = biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return shelf, highpass
+}
+
+// Loudness returns the integrated loudness of a render in LUFS, following
+// ITU-R BS.1770-4: K-weighted 400 ms blocks overlapping by 75%, gated at -70
+// LUFS and then 10 LU below the ungated mean. All channels are weighted
+// equally, and a channel shorter than the others counts as silent after its
+// end. Silence returns -Inf.
+func Loudness(channels [][]float32, sampleRate int) float64 {
+	if len(channels) == 0 || sampleRate <= 0 {
+		return math.Inf(-1)
+	}
+	n := 0
+	for _, ch := range channels {
+		if len(ch) > n {
+			n = len(ch)
+		}
+	}
+
+	// mean square of the K-weighted signal per 100 ms step, summed over
+	// channels
+	step := sampleRate / 10
+	if step == 0 {
+		step = 1
+	}
+	steps := (n + step - 1) / step
+	power := make([]float64, steps)
+	for _, ch := range channels {
+		shelf, highpass := kWeighting(sampleRate)
+		for i, v := range ch {
+			y := highpass.process(shelf.process(float64(v)))
+			power[i/step] += y * y
+		}
+	}
+
+	// 400 ms blocks are four consecutive steps, a shorter render is one block
+	blockLen := 4
+	if steps < blockLen {
+		blockLen = steps
+	}
+	var blocks []float64
+	for start := 0; start+blockLen <= steps; start++ {
+		var sum float64
+		frames := 0
+		for i := start; i < start+blockLen; i++ {
+			sum += power[i]
+			if i == steps-1 && n%step != 0 {
+				frames += n % step
+			} else {
+				frames += step
+			}
+		}
+		blocks = append(blocks, sum/float64(frames))
+	}
+
+	toLUFS := func(z float64) float64 {
+		return -0.691 + 10*math.Log10(z)
+	}
+	gatedMean := func(threshold float64) (float64, int) {
+		var sum float64
+		count := 0
+		for _, z := range blocks {
+			if toLUFS(z) > threshold {
+				sum += z
+				count++
+			}
+		}
+		if count == 0 {
+			return 0, 0
+		}
+		return sum / float64(count), count
+	}
+
+	mean, count := gatedMean(-70)
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	mean, count = gatedMean(toLUFS(mean) - 10)
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	return toLUFS(mean)
+}
+
+// NormalizeLoudness scales a render in place so its integrated loudness is
+// target LUFS, returning the gain applied in dB. Silence is left untouched.
+// Raising the level can clip, follow with SoftLimit.
+func NormalizeLoudness(channels [][]float32, sampleRate int, target float64) float64 {
+	loudness := Loudness(channels, sampleRate)
+	if math.IsInf(loudness, -1) {
+		return 0
+	}
+
+	gainDB := target - loudness
+	gain := float32(math.Pow(10, gainDB/20))
+	for _, ch := range channels {
+		for i := range ch {
+			ch[i] *= gain
+		}
+	}
+	return gainDB
+}
+
+// SoftLimit bends every sample above threshold (linear, below 1) smoothly
+// towards full scale so the output never exceeds it. Samples below the
+// threshold are untouched. It returns how many samples were limited.
+func SoftLimit(channels [][]float32, threshold float64) int {
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.9
+	}
+	knee := 1 - threshold
+
+	limited := 0
+	for _, ch := range channels {
+		for i, v := range ch {
+			mag := math.Abs(float64(v))
+			if mag <= threshold {
+				continue
+			}
+			out := threshold + knee*math.Tanh((mag-threshold)/knee)
+			ch[i] = float32(math.Copysign(out, float64(v)))
+			limited++
+		}
+	}
+	return limited
+}
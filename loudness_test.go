@@ -0,0 +1,32 @@
+package sf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoudnessUnequalChannels(t *testing.T) {
+	sine := func(n int) []float32 {
+		s := make([]float32, n)
+		for i := range s {
+			s[i] = float32(0.5 * math.Sin(2*math.Pi*997*float64(i)/48000))
+		}
+		return s
+	}
+	long, short := sine(48000*2), sine(48000)
+
+	// a full scale 997 Hz tone in one channel measures -3.01 LUFS, so at
+	// -6 dBFS it is -9.03
+	if got := Loudness([][]float32{long}, 48000); math.Abs(got+9.03) > 0.05 {
+		t.Errorf("tone measured %.2f LUFS, want -9.03", got)
+	}
+
+	a := Loudness([][]float32{short, long}, 48000)
+	b := Loudness([][]float32{long, short}, 48000)
+	if math.IsNaN(a) || math.IsInf(a, 0) || a != b {
+		t.Errorf("channel order changed the loudness: %v and %v", a, b)
+	}
+	if both := Loudness([][]float32{long, long}, 48000); a >= both {
+		t.Errorf("a channel falling silent halfway measured %.2f LUFS, no quieter than %.2f", a, both)
+	}
+}
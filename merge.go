@@ -0,0 +1,547 @@
+package sf
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+)
+
+// Conflict is a change made on both sides of a merge that could not be
+// reconciled. The merged result keeps our side.
+type Conflict struct {
+	// Kind is "info", "preset", "instrument" or "sample".
+	Kind string
+	// Key identifies the object: the INFO field name, "bank:program" for
+	// presets, or the instrument or sample name.
+	Key string
+	// Zone is the index of the conflicting zone, or -1 when the object as a
+	// whole conflicts.
+	Zone int
+	// Base, Ours and Theirs are the three versions, nil where the object is
+	// absent.
+	Base, Ours, Theirs interface{}
+}
+
+func (c Conflict) String() string {
+	if c.Zone >= 0 {
+		return fmt.Sprintf("%s %q zone %d changed on both sides", c.Kind, c.Key, c.Zone)
+	}
+	return fmt.Sprintf("%s %q changed on both sides", c.Kind, c.Key)
+}
+
+// mergeSide is one of the three versions taking part in a merge, with its
+// objects keyed by identity instead of index.
+type mergeSide struct {
+	sf     *SoundFont
+	layout *Layout
+
+	presetKeys, instKeys, sampleKeys []string
+	presets, insts, samples          map[string]int
+}
+
+// uniqueKeys returns a key per name, suffixing repeated names with their
+// occurrence so every key is unique.
+func uniqueKeys(names []string) ([]string, map[string]int) {
+	keys := make([]string, len(names))
+	index := make(map[string]int, len(names))
+	seen := make(map[string]int)
+	for i, name := range names {
+		key := name
+		if n := seen[name]; n > 0 {
+			key = fmt.Sprintf("%s#%d", name, n)
+		}
+		seen[name]++
+		keys[i] = key
+		index[key] = i
+	}
+	return keys, index
+}
+
+func newMergeSide(sf *SoundFont) (*mergeSide, error) {
+	s := &mergeSide{sf: sf, layout: &Layout{}}
+	if sf.Hydra != nil {
+		l, err := sf.Hydra.Unpack()
+		if err != nil {
+			return nil, err
+		}
+		s.layout = l
+	}
+
+	var names []string
+	for _, p := range s.layout.Presets {
+		names = append(names, fmt.Sprintf("%d:%d", p.Header.Bank, p.Header.Preset))
+	}
+	s.presetKeys, s.presets = uniqueKeys(names)
+
+	names = nil
+	for _, inst := range s.layout.Instruments {
+		names = append(names, trimName(inst.Name))
+	}
+	s.instKeys, s.insts = uniqueKeys(names)
+
+	names = nil
+	for _, smp := range s.layout.Samples {
+		names = append(names, trimName(smp.SampleName))
+	}
+	s.sampleKeys, s.samples = uniqueKeys(names)
+
+	return s, nil
+}
+
+// key returns keys[i], or "" when i is out of range.
+func key(keys []string, i int) string {
+	if i < 0 || i >= len(keys) {
+		return ""
+	}
+	return keys[i]
+}
+
+// zoneSig describes a zone with its instrument and sample references replaced
+// by keys, so zones from different files can be compared.
+func (s *mergeSide) zoneSig(z Zone) string {
+	var b strings.Builder
+	for _, g := range z.Generators {
+		switch g.GenOper {
+		case Gen_Instrument:
+			fmt.Fprintf(&b, "inst=%q;", key(s.instKeys, int(uint16(g.GenAmount))))
+		case Gen_SampleID:
+			fmt.Fprintf(&b, "sample=%q;", key(s.sampleKeys, int(uint16(g.GenAmount))))
+		default:
+			fmt.Fprintf(&b, "%d=%d;", g.GenOper, g.GenAmount)
+		}
+	}
+	for _, m := range z.Modulators {
+		fmt.Fprintf(&b, "mod%v;", m)
+	}
+	return b.String()
+}
+
+func (s *mergeSide) headerSig(k string) (string, bool) {
+	i, ok := s.presets[k]
+	if !ok {
+		return "", false
+	}
+	h := s.layout.Presets[i].Header
+	h.PresetBagNdx = 0
+	return fmt.Sprintf("%v", h), true
+}
+
+func (s *mergeSide) presetSig(k string) (string, bool) {
+	sig, ok := s.headerSig(k)
+	if !ok {
+		return "", false
+	}
+	for _, z := range s.layout.Presets[s.presets[k]].Zones {
+		sig += "|" + s.zoneSig(z)
+	}
+	return sig, true
+}
+
+func (s *mergeSide) instSig(k string) (string, bool) {
+	i, ok := s.insts[k]
+	if !ok {
+		return "", false
+	}
+	sig := fmt.Sprintf("%q", s.layout.Instruments[i].Name)
+	for _, z := range s.layout.Instruments[i].Zones {
+		sig += "|" + s.zoneSig(z)
+	}
+	return sig, true
+}
+
+func (s *mergeSide) sampleSig(k string) (string, bool) {
+	i, ok := s.samples[k]
+	if !ok {
+		return "", false
+	}
+	h := s.layout.Samples[i]
+	higher, lower := s.sf.SampleData(h)
+
+	sum := fnv.New64a()
+	for _, v := range higher {
+		sum.Write([]byte{byte(v), byte(v >> 8)})
+	}
+	for _, v := range lower {
+		sum.Write([]byte{byte(v)})
+	}
+
+	// positions are compared relative to the sample, links by name
+	link := key(s.sampleKeys, int(h.SampleLink))
+	loopStart, loopEnd := int64(h.Startloop)-int64(h.Start), int64(h.Endloop)-int64(h.Start)
+	h.Start, h.End, h.Startloop, h.Endloop, h.SampleLink = 0, 0, 0, 0, 0
+	return fmt.Sprintf("%v|%d|%d|%q|%x", h, loopStart, loopEnd, link, sum.Sum64()), true
+}
+
+// choice is the outcome of a three-way decision.
+type choice int
+
+const (
+	chooseOurs choice = iota
+	chooseTheirs
+	chooseConflict
+)
+
+// decide picks a side given the three signatures of an object, where ok is
+// false for an absent object.
+func decide(b string, bOk bool, o string, oOk bool, t string, tOk bool) choice {
+	switch {
+	case oOk == tOk && o == t:
+		return chooseOurs
+	case oOk == bOk && o == b:
+		return chooseTheirs
+	case tOk == bOk && t == b:
+		return chooseOurs
+	}
+	return chooseConflict
+}
+
+// mergedZone is a zone of the result, along with the side its references
+// point into.
+type mergedZone struct {
+	side *mergeSide
+	zone Zone
+}
+
+type mergedPreset struct {
+	header PresetHeader
+	zones  []mergedZone
+}
+
+type mergedInstrument struct {
+	name  [20]byte
+	zones []mergedZone
+}
+
+// sideZones pairs every zone with its side.
+func sideZones(s *mergeSide, zones []Zone) []mergedZone {
+	out := make([]mergedZone, len(zones))
+	for i, z := range zones {
+		out[i] = mergedZone{s, z}
+	}
+	return out
+}
+
+// orderedKeys returns the keys of ours, then the keys only theirs has.
+func orderedKeys(ours, theirs []string, oursIndex map[string]int) []string {
+	keys := append([]string(nil), ours...)
+	for _, k := range theirs {
+		if _, ok := oursIndex[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// mergeZones merges the zones of an object present on all three sides with
+// the same number of zones, zone by zone. It returns nil when the zone lists
+// differ in length and cannot be merged this way.
+func mergeZones(kind, objKey string, b, o, t *mergeSide, bz, oz, tz []Zone, conflicts *[]Conflict) []mergedZone {
+	if len(bz) != len(oz) || len(oz) != len(tz) {
+		return nil
+	}
+
+	out := make([]mergedZone, len(oz))
+	for i := range oz {
+		bs, os, ts := b.zoneSig(bz[i]), o.zoneSig(oz[i]), t.zoneSig(tz[i])
+		switch decide(bs, true, os, true, ts, true) {
+		case chooseTheirs:
+			out[i] = mergedZone{t, tz[i]}
+		case chooseConflict:
+			*conflicts = append(*conflicts, Conflict{kind, objKey, i, bz[i], oz[i], tz[i]})
+			fallthrough
+		default:
+			out[i] = mergedZone{o, oz[i]}
+		}
+	}
+	return out
+}
+
+// mergeInfo merges the INFO fields one by one.
+func mergeInfo(b, o, t *SoundFontInfo, conflicts *[]Conflict) *SoundFontInfo {
+	if o == nil || t == nil || b == nil {
+		switch {
+		case reflect.DeepEqual(o, t), reflect.DeepEqual(t, b):
+			return o
+		case reflect.DeepEqual(o, b):
+			return t
+		}
+		*conflicts = append(*conflicts, Conflict{"info", "INFO", -1, b, o, t})
+		return o
+	}
+
+	merged := *o
+	bv, ov, tv := reflect.ValueOf(*b), reflect.ValueOf(*o), reflect.ValueOf(*t)
+	mv := reflect.ValueOf(&merged).Elem()
+	for i := 0; i < mv.NumField(); i++ {
+		bf, of, tf := bv.Field(i).Interface(), ov.Field(i).Interface(), tv.Field(i).Interface()
+		switch {
+		case reflect.DeepEqual(of, tf), reflect.DeepEqual(tf, bf):
+		case reflect.DeepEqual(of, bf):
+			mv.Field(i).Set(tv.Field(i))
+		default:
+			*conflicts = append(*conflicts, Conflict{"info", mv.Type().Field(i).Name, -1, bf, of, tf})
+		}
+	}
+	return &merged
+}
+
+// Merge3 merges the changes made in ours and theirs since their common
+// ancestor base. Presets are matched by bank and program, instruments and
+// samples by name. An object changed on both sides is merged zone by zone when
+// its zone count did not change, otherwise (or when the same zone changed on
+// both sides) our version is kept and a Conflict is reported.
+//
+// Instruments and samples deleted on one side but still used by the merged
+// presets or instruments are kept. A stereo sample whose other half is not
+// in the result becomes mono. A zone playing an instrument or sample that
+// does not exist on its side is an error.
+func Merge3(base, ours, theirs *SoundFont) (*SoundFont, []Conflict, error) {
+	b, err := newMergeSide(base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base: %w", err)
+	}
+	o, err := newMergeSide(ours)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ours: %w", err)
+	}
+	t, err := newMergeSide(theirs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("theirs: %w", err)
+	}
+
+	var conflicts []Conflict
+	result := &SoundFont{Info: mergeInfo(base.Info, ours.Info, theirs.Info, &conflicts)}
+
+	// presets
+	var presets []mergedPreset
+	for _, k := range orderedKeys(o.presetKeys, t.presetKeys, o.presets) {
+		bs, bOk := b.presetSig(k)
+		os, oOk := o.presetSig(k)
+		ts, tOk := t.presetSig(k)
+
+		pick := func(s *mergeSide) {
+			if i, ok := s.presets[k]; ok {
+				p := s.layout.Presets[i]
+				presets = append(presets, mergedPreset{p.Header, sideZones(s, p.Zones)})
+			}
+		}
+
+		switch decide(bs, bOk, os, oOk, ts, tOk) {
+		case chooseOurs:
+			pick(o)
+		case chooseTheirs:
+			pick(t)
+		case chooseConflict:
+			if !bOk || !oOk || !tOk {
+				conflicts = append(conflicts, Conflict{"preset", k, -1, presetOrNil(b, k), presetOrNil(o, k), presetOrNil(t, k)})
+				pick(o)
+				continue
+			}
+
+			bp, op, tp := b.layout.Presets[b.presets[k]], o.layout.Presets[o.presets[k]], t.layout.Presets[t.presets[k]]
+			zones := mergeZones("preset", k, b, o, t, bp.Zones, op.Zones, tp.Zones, &conflicts)
+			if zones == nil {
+				conflicts = append(conflicts, Conflict{"preset", k, -1, &bp, &op, &tp})
+				pick(o)
+				continue
+			}
+
+			header := op.Header
+			bh, _ := b.headerSig(k)
+			oh, _ := o.headerSig(k)
+			th, _ := t.headerSig(k)
+			switch decide(bh, true, oh, true, th, true) {
+			case chooseTheirs:
+				header = tp.Header
+			case chooseConflict:
+				conflicts = append(conflicts, Conflict{"preset", k, -1, bp.Header, op.Header, tp.Header})
+			}
+			presets = append(presets, mergedPreset{header, zones})
+		}
+	}
+
+	// instruments, including the ones the merged presets still use
+	var instruments []mergedInstrument
+	instIndex := make(map[string]int)
+	addInst := func(k string, inst mergedInstrument) {
+		if _, ok := instIndex[k]; !ok {
+			instIndex[k] = len(instruments)
+			instruments = append(instruments, inst)
+		}
+	}
+	for _, k := range orderedKeys(o.instKeys, t.instKeys, o.insts) {
+		bs, bOk := b.instSig(k)
+		os, oOk := o.instSig(k)
+		ts, tOk := t.instSig(k)
+
+		pick := func(s *mergeSide) {
+			if i, ok := s.insts[k]; ok {
+				inst := s.layout.Instruments[i]
+				addInst(k, mergedInstrument{inst.Name, sideZones(s, inst.Zones)})
+			}
+		}
+
+		switch decide(bs, bOk, os, oOk, ts, tOk) {
+		case chooseOurs:
+			pick(o)
+		case chooseTheirs:
+			pick(t)
+		case chooseConflict:
+			if !bOk || !oOk || !tOk {
+				conflicts = append(conflicts, Conflict{"instrument", k, -1, instOrNil(b, k), instOrNil(o, k), instOrNil(t, k)})
+				pick(o)
+				continue
+			}
+
+			bi, oi, ti := b.layout.Instruments[b.insts[k]], o.layout.Instruments[o.insts[k]], t.layout.Instruments[t.insts[k]]
+			zones := mergeZones("instrument", k, b, o, t, bi.Zones, oi.Zones, ti.Zones, &conflicts)
+			if zones == nil {
+				conflicts = append(conflicts, Conflict{"instrument", k, -1, &bi, &oi, &ti})
+				pick(o)
+				continue
+			}
+			addInst(k, mergedInstrument{oi.Name, zones})
+		}
+	}
+	for _, p := range presets {
+		for _, z := range p.zones {
+			if i, ok := z.zone.terminal(Gen_Instrument); ok {
+				k := key(z.side.instKeys, int(uint16(i)))
+				if idx, ok := z.side.insts[k]; ok {
+					inst := z.side.layout.Instruments[idx]
+					addInst(k, mergedInstrument{inst.Name, sideZones(z.side, inst.Zones)})
+				}
+			}
+		}
+	}
+
+	// samples, including the ones the merged instruments still use
+	type mergedSample struct {
+		side  *mergeSide
+		index int
+	}
+	var samples []mergedSample
+	sampleIndex := make(map[string]int)
+	addSample := func(k string, s *mergeSide) {
+		if _, ok := sampleIndex[k]; ok {
+			return
+		}
+		if i, ok := s.samples[k]; ok {
+			sampleIndex[k] = len(samples)
+			samples = append(samples, mergedSample{s, i})
+		}
+	}
+	for _, k := range orderedKeys(o.sampleKeys, t.sampleKeys, o.samples) {
+		bs, bOk := b.sampleSig(k)
+		os, oOk := o.sampleSig(k)
+		ts, tOk := t.sampleSig(k)
+		switch decide(bs, bOk, os, oOk, ts, tOk) {
+		case chooseOurs:
+			addSample(k, o)
+		case chooseTheirs:
+			addSample(k, t)
+		case chooseConflict:
+			conflicts = append(conflicts, Conflict{"sample", k, -1, sampleOrNil(b, k), sampleOrNil(o, k), sampleOrNil(t, k)})
+			addSample(k, o)
+		}
+	}
+	for _, inst := range instruments {
+		for _, z := range inst.zones {
+			if i, ok := z.zone.terminal(Gen_SampleID); ok {
+				addSample(key(z.side.sampleKeys, int(uint16(i))), z.side)
+			}
+		}
+	}
+
+	// rebuild the hydra and sample data with the merged objects' references
+	// pointing at their new indices
+	layout := &Layout{}
+	pool := &samplePool{}
+	for _, ms := range samples {
+		h := ms.side.layout.Samples[ms.index]
+		higher, lower := ms.side.sf.SampleData(h)
+		h = pool.add(h, higher, lower)
+		if h.SampleType&^0x8000 != SampleType_Mono {
+			if i, ok := sampleIndex[key(ms.side.sampleKeys, int(h.SampleLink))]; ok {
+				h.SampleLink = uint16(i)
+			} else {
+				// the other half of the pair did not make it into the
+				// merge, so this one plays on its own
+				h.SampleLink = 0
+				h.SampleType = h.SampleType&0x8000 | SampleType_Mono
+			}
+		}
+		layout.Samples = append(layout.Samples, h)
+	}
+
+	remap := func(z mergedZone) (Zone, error) {
+		out := copyZone(z.zone)
+		for i, g := range out.Generators {
+			switch g.GenOper {
+			case Gen_Instrument:
+				j, ok := instIndex[key(z.side.instKeys, int(uint16(g.GenAmount)))]
+				if !ok {
+					return out, fmt.Errorf("a zone plays instrument %d, which does not exist", uint16(g.GenAmount))
+				}
+				out.Generators[i].GenAmount = GenAmount(j)
+			case Gen_SampleID:
+				j, ok := sampleIndex[key(z.side.sampleKeys, int(uint16(g.GenAmount)))]
+				if !ok {
+					return out, fmt.Errorf("a zone plays sample %d, which does not exist", uint16(g.GenAmount))
+				}
+				out.Generators[i].GenAmount = GenAmount(j)
+			}
+		}
+		return out, nil
+	}
+	for _, inst := range instruments {
+		data := InstrumentData{Name: inst.name}
+		for _, z := range inst.zones {
+			zone, err := remap(z)
+			if err != nil {
+				return nil, nil, fmt.Errorf("instrument %q: %w", trimName(inst.name), err)
+			}
+			data.Zones = append(data.Zones, zone)
+		}
+		layout.Instruments = append(layout.Instruments, data)
+	}
+	for _, p := range presets {
+		data := PresetData{Header: p.header}
+		for _, z := range p.zones {
+			zone, err := remap(z)
+			if err != nil {
+				return nil, nil, fmt.Errorf("preset %d:%d: %w", p.header.Bank, p.header.Preset, err)
+			}
+			data.Zones = append(data.Zones, zone)
+		}
+		layout.Presets = append(layout.Presets, data)
+	}
+
+	if result.Hydra, err = layout.Pack(); err != nil {
+		return nil, nil, err
+	}
+	result.Samples = pool.samples()
+	return result, conflicts, nil
+}
+
+func presetOrNil(s *mergeSide, k string) interface{} {
+	if i, ok := s.presets[k]; ok {
+		return &s.layout.Presets[i]
+	}
+	return nil
+}
+
+func instOrNil(s *mergeSide, k string) interface{} {
+	if i, ok := s.insts[k]; ok {
+		return &s.layout.Instruments[i]
+	}
+	return nil
+}
+
+func sampleOrNil(s *mergeSide, k string) interface{} {
+	if i, ok := s.samples[k]; ok {
+		return &s.layout.Samples[i]
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package sf
+
+import "testing"
+
+// editBank applies edit to the unpacked layout of bank.
+func editBank(t *testing.T, bank *SoundFont, edit func(l *Layout)) *SoundFont {
+	t.Helper()
+	l, err := bank.Hydra.Unpack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(l)
+	if bank.Hydra, err = l.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	return bank
+}
+
+// setGen sets op in the first zone of the named instrument.
+func setGen(l *Layout, inst string, op SFGenerator, v int16) {
+	for i := range l.Instruments {
+		if trimName(l.Instruments[i].Name) != inst {
+			continue
+		}
+		z := &l.Instruments[i].Zones[0]
+		z.Generators = append([]Generator{{GenOper: op, GenAmount: GenAmount(v)}}, z.Generators...)
+	}
+}
+
+// instGen returns the value of op in the first zone of the named instrument.
+func instGen(t *testing.T, bank *SoundFont, inst string, op SFGenerator) (int16, bool) {
+	t.Helper()
+	l, err := bank.Hydra.Unpack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range l.Instruments {
+		if trimName(in.Name) != inst {
+			continue
+		}
+		for _, g := range in.Zones[0].Generators {
+			if g.GenOper == op {
+				return g.GenAmount.AsInt16(), true
+			}
+		}
+		return 0, false
+	}
+	t.Fatalf("no instrument %q", inst)
+	return 0, false
+}
+
+func TestMerge3OneSided(t *testing.T) {
+	base := GenerateSineBank(3)
+	ours := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 0", Gen_Pan, 250) })
+	theirs := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 1", Gen_FineTune, 12) })
+
+	merged, conflicts, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("unexpected conflicts %v", conflicts)
+	}
+	if v, ok := instGen(t, merged, "Sine 0", Gen_Pan); !ok || v != 250 {
+		t.Errorf("our pan edit is %d, %v in the merge", v, ok)
+	}
+	if v, ok := instGen(t, merged, "Sine 1", Gen_FineTune); !ok || v != 12 {
+		t.Errorf("their fineTune edit is %d, %v in the merge", v, ok)
+	}
+	if problems := merged.Hydra.Validate(); len(problems) != 0 {
+		t.Errorf("merged hydra is invalid: %v", problems)
+	}
+}
+
+func TestMerge3Conflict(t *testing.T) {
+	base := GenerateSineBank(3)
+	ours := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 1", Gen_Pan, -100) })
+	theirs := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 1", Gen_Pan, 100) })
+
+	merged, conflicts, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Kind != "instrument" || conflicts[0].Key != "Sine 1" || conflicts[0].Zone != 0 {
+		t.Fatalf("got conflicts %v, want instrument \"Sine 1\" zone 0", conflicts)
+	}
+	if v, _ := instGen(t, merged, "Sine 1", Gen_Pan); v != -100 {
+		t.Errorf("conflicting zone has pan %d, want ours", v)
+	}
+}
+
+func TestMerge3DeletedSampleStillUsed(t *testing.T) {
+	base := GenerateSineBank(3)
+	ours := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 2", Gen_Pan, 300) })
+	// the last preset, instrument and sample go, so no indices move
+	theirs := editBank(t, GenerateSineBank(3), func(l *Layout) {
+		l.Presets = l.Presets[:2]
+		l.Instruments = l.Instruments[:2]
+		l.Samples = l.Samples[:2]
+	})
+
+	merged, conflicts, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the preset theirs deleted is unchanged on our side, so it goes
+	if len(conflicts) != 1 || conflicts[0].Key != "Sine 2" {
+		t.Errorf("got conflicts %v, want the instrument edited on one side and deleted on the other", conflicts)
+	}
+	l, err := merged.Hydra.Unpack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range l.Instruments {
+		if trimName(in.Name) != "Sine 2" {
+			continue
+		}
+		id, ok := in.Zones[0].terminal(Gen_SampleID)
+		if !ok || int(id) >= len(l.Samples) || trimName(l.Samples[id].SampleName) != "Sine 2" {
+			t.Errorf("instrument \"Sine 2\" plays sample %d, not the one theirs deleted", id)
+		}
+		return
+	}
+	t.Error("our edited instrument was dropped")
+}
+
+func TestMerge3HalfStereoPair(t *testing.T) {
+	stereo := func() *SoundFont {
+		bank := GenerateSineBank(1)
+		return editBank(t, bank, func(l *Layout) {
+			left := l.Samples[0]
+			left.SampleName = fixedName("left")
+			left.SampleType, left.SampleLink = SampleType_Left, 1
+			right := left
+			right.SampleName = fixedName("right")
+			right.SampleType, right.SampleLink = SampleType_Right, 0
+			l.Samples = []SampleHeader{left, right}
+		})
+	}
+	base, ours := stereo(), stereo()
+	// theirs drops the right half, which no instrument plays
+	theirs := editBank(t, stereo(), func(l *Layout) { l.Samples = l.Samples[:1] })
+
+	merged, _, err := Merge3(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := merged.Hydra.Samples[:len(merged.Hydra.Samples)-1]
+	if len(samples) != 1 {
+		t.Fatalf("merge holds %d samples, want the left one", len(samples))
+	}
+	if s := samples[0]; s.SampleType != SampleType_Mono || s.SampleLink != 0 {
+		t.Errorf("lone half of a stereo pair has type %v and link %d, want mono and 0", s.SampleType, s.SampleLink)
+	}
+}
+
+func TestMerge3MissingReference(t *testing.T) {
+	base := GenerateSineBank(2)
+	ours := editBank(t, GenerateSineBank(2), func(l *Layout) {
+		l.Presets[0].Zones[0].Generators[0].GenAmount = 99
+	})
+	if _, _, err := Merge3(base, ours, GenerateSineBank(2)); err == nil {
+		t.Error("merged a preset playing an instrument that does not exist")
+	}
+}
@@ -0,0 +1,57 @@
+package sf
+
+import "sync"
+
+// Metrics receives counters, gauges and observations from the package, for
+// integrators to forward to their monitoring system. Implementations must be
+// safe for concurrent use.
+type Metrics interface {
+	// Count adds delta to a counter.
+	Count(name string, delta float64)
+
+	// Gauge sets a gauge to value.
+	Gauge(name string, value float64)
+
+	// Observe records one observation, such as a duration in seconds, for a
+	// histogram or summary.
+	Observe(name string, value float64)
+}
+
+// Metric names reported by the package.
+const (
+	// MetricLoads counts calls to ReadSoundFont.
+	MetricLoads = "sf_loads_total"
+	// MetricParseFailures counts loads that returned an error.
+	MetricParseFailures = "sf_parse_failures_total"
+	// MetricLoadSeconds observes the duration of successful loads.
+	MetricLoadSeconds = "sf_load_seconds"
+)
+
+// nopMetrics discards everything.
+type nopMetrics struct{}
+
+func (nopMetrics) Count(string, float64)   {}
+func (nopMetrics) Gauge(string, float64)   {}
+func (nopMetrics) Observe(string, float64) {}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   Metrics = nopMetrics{}
+)
+
+// SetMetrics directs the package's metrics to m. A nil m turns metrics off,
+// which is the default.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics = m
+}
+
+func currentMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metrics
+}
@@ -0,0 +1,340 @@
+package sf
+
+import (
+	"fmt"
+	"math"
+)
+
+// Model is a bank described independently of any file format: instruments
+// made of regions that play samples with an articulation in natural units.
+// Format backends convert to and from a Model, so that n formats need n
+// converters rather than one per pair. What a format can express but a Model
+// cannot, such as SoundFont modulators and key scaled envelopes, is dropped
+// on the way.
+type Model struct {
+	Name        string
+	Samples     []ModelSample
+	Instruments []ModelInstrument
+}
+
+// ModelChannel is which side of a stereo pair a sample is.
+type ModelChannel int
+
+const (
+	ModelChannel_Mono ModelChannel = iota
+	ModelChannel_Left
+	ModelChannel_Right
+)
+
+// ModelSample is a sample of a Model.
+type ModelSample struct {
+	Name string
+	Rate uint32
+
+	// Higher holds the sample points, Lower the optional low bytes of 24-bit
+	// samples.
+	Higher []int16
+	Lower  []int8
+
+	// LoopStart and LoopEnd are sample point offsets from the start.
+	LoopStart, LoopEnd uint32
+
+	// RootKey is the key the sample was recorded at, Correction the cents it
+	// is off from it.
+	RootKey    uint8
+	Correction int8
+
+	Channel ModelChannel
+	// Link is the index of the other side of a stereo pair, -1 for none.
+	Link int
+}
+
+// ModelInstrument is a playable instrument of a Model, a preset in SoundFont
+// terms.
+type ModelInstrument struct {
+	Name          string
+	Bank, Program uint16
+	Regions       []ModelRegion
+}
+
+// ModelRegion is a sample mapped to a range of keys and velocities.
+type ModelRegion struct {
+	// Sample indexes Model.Samples.
+	Sample int
+
+	KeyLo, KeyHi, VelLo, VelHi uint8
+
+	// RootKey is the key that plays the sample at its own pitch.
+	RootKey uint8
+
+	// Start, End, LoopStart and LoopEnd are the part of the sample played,
+	// as sample point offsets from its start.
+	Start, End, LoopStart, LoopEnd uint32
+
+	Articulation Articulation
+}
+
+// Articulation is how a region shapes its sample.
+type Articulation struct {
+	// Tune is in cents, ScaleTuning the cents per key.
+	Tune        int
+	ScaleTuning int
+
+	// Attenuation is in dB, Pan from -1 (left) to 1 (right).
+	Attenuation float64
+	Pan         float64
+
+	// FilterCutoff is the lowpass cutoff in Hz, FilterResonance its peak in
+	// dB.
+	FilterCutoff    float64
+	FilterResonance float64
+
+	// Reverb and Chorus are the effect sends, 0 to 1.
+	Reverb, Chorus float64
+
+	LoopMode SampleMode
+
+	AmpEnvelope, ModEnvelope Envelope
+	// ModEnvToPitch and ModEnvToFilter are in cents at full envelope.
+	ModEnvToPitch, ModEnvToFilter int
+
+	// ExclusiveClass, when not 0, cuts off other regions of the class.
+	ExclusiveClass int
+}
+
+// ToModel converts a SoundFont to a Model, one instrument per preset with its
+// regions flattened from the preset and instrument zones. ROM samples cannot
+// be carried over and are an error when played.
+func ToModel(sf *SoundFont) (*Model, error) {
+	m := &Model{}
+	if sf.Info != nil {
+		m.Name = sf.Info.Name
+	}
+
+	h := sf.Hydra
+	index := make([]int, len(h.Samples))
+	for i := 0; i+1 < len(h.Samples); i++ {
+		s := h.Samples[i]
+		if s.isROM() {
+			index[i] = -1
+			continue
+		}
+		index[i] = len(m.Samples)
+		higher, lower := sf.SampleData(s)
+		ms := ModelSample{
+			Name:       trimName(s.SampleName),
+			Rate:       s.SampleRate,
+			Higher:     higher,
+			Lower:      lower,
+			RootKey:    s.OriginalPitch,
+			Correction: s.PitchCorrection,
+			Link:       int(s.SampleLink),
+		}
+		if s.Startloop >= s.Start {
+			ms.LoopStart, ms.LoopEnd = s.Startloop-s.Start, s.Endloop-s.Start
+		}
+		switch s.SampleType {
+		case SampleType_Left:
+			ms.Channel = ModelChannel_Left
+		case SampleType_Right:
+			ms.Channel = ModelChannel_Right
+		default:
+			ms.Link = -1
+		}
+		m.Samples = append(m.Samples, ms)
+	}
+	for i := range m.Samples {
+		if l := m.Samples[i].Link; l >= 0 {
+			if l < len(index) && index[l] >= 0 {
+				m.Samples[i].Link = index[l]
+			} else {
+				m.Samples[i].Link, m.Samples[i].Channel = -1, ModelChannel_Mono
+			}
+		}
+	}
+
+	for p := 0; p+1 < len(h.Headers); p++ {
+		hd := h.Headers[p]
+		regions, err := h.PresetRegions(p)
+		if err != nil {
+			return nil, err
+		}
+		inst := ModelInstrument{Name: trimName(hd.PresetName), Bank: hd.Bank, Program: hd.Preset}
+		for _, r := range regions {
+			if r.SampleIndex >= len(index) || index[r.SampleIndex] < 0 {
+				return nil, fmt.Errorf("preset %q plays ROM sample %q", inst.Name, trimName(r.Sample.SampleName))
+			}
+			inst.Regions = append(inst.Regions, modelRegion(r, index[r.SampleIndex]))
+		}
+		m.Instruments = append(m.Instruments, inst)
+	}
+	return m, nil
+}
+
+// modelRegion converts a resolved region playing model sample i.
+func modelRegion(r Region, i int) ModelRegion {
+	mr := ModelRegion{Sample: i, RootKey: r.Sample.OriginalPitch}
+	mr.KeyLo, mr.KeyHi = rangeBytes(r.Gen(Gen_KeyRange))
+	mr.VelLo, mr.VelHi = rangeBytes(r.Gen(Gen_VelRange))
+	if k := r.Gen(Gen_OverridingRootKey); k >= 0 && k <= 127 {
+		mr.RootKey = uint8(k)
+	}
+
+	a := r.Addresses()
+	rel := func(v uint32) uint32 { return v - r.Sample.Start }
+	mr.Start, mr.End, mr.LoopStart, mr.LoopEnd = rel(a.Start), rel(a.End), rel(a.Startloop), rel(a.Endloop)
+
+	// envelopes without key scaling
+	r.Key = 60
+	mr.Articulation = Articulation{
+		Tune:            100*int(r.Gen(Gen_CoarseTune)) + int(r.Gen(Gen_FineTune)),
+		ScaleTuning:     int(r.Gen(Gen_ScaleTuning)),
+		Attenuation:     float64(r.Gen(Gen_InitialAttenuation)) / 10,
+		Pan:             float64(r.Gen(Gen_Pan)) / 500,
+		FilterCutoff:    absoluteCentsToHz(int(r.Gen(Gen_InitialFilterFc))),
+		FilterResonance: float64(r.Gen(Gen_InitialFilterQ)) / 10,
+		Reverb:          float64(r.Gen(Gen_ReverbEffectsSend)) / 1000,
+		Chorus:          float64(r.Gen(Gen_ChorusEffectsSend)) / 1000,
+		LoopMode:        SampleMode(uint16(r.Gen(Gen_SampleModes)) & 3),
+		AmpEnvelope:     r.VolumeEnvelope(),
+		ModEnvelope:     r.ModulationEnvelope(),
+		ModEnvToPitch:   int(r.Gen(Gen_ModEnvToPitch)),
+		ModEnvToFilter:  int(r.Gen(Gen_ModEnvToFilterFc)),
+		ExclusiveClass:  int(r.Gen(Gen_ExclusiveClass)),
+	}
+	return mr
+}
+
+// envelopeGenerators converts an envelope to the six generators starting at
+// delay.
+func envelopeGenerators(e Envelope, delay SFGenerator) []Generator {
+	var sustain int16
+	if e.Volume {
+		sustain = clampInt16(int(math.Round(math.Min(gainToCentibels(e.Sustain), 1000))))
+	} else {
+		sustain = clampInt16(int(math.Round(1000 * (1 - e.Sustain))))
+	}
+	return []Generator{
+		{delay, GenAmount(secondsToTimecents(e.Delay))},
+		{delay + 1, GenAmount(secondsToTimecents(e.Attack))},
+		{delay + 2, GenAmount(secondsToTimecents(e.Hold))},
+		{delay + 3, GenAmount(secondsToTimecents(e.Decay))},
+		{delay + 4, GenAmount(sustain)},
+		{delay + 5, GenAmount(secondsToTimecents(e.Release))},
+	}
+}
+
+// regionZone converts a region to an instrument zone playing sample header
+// s, leaving out generators at their default.
+func regionZone(r ModelRegion, s SampleHeader) Zone {
+	a := r.Articulation
+	gens := []Generator{
+		{Gen_KeyRange, RangeAmount(r.KeyLo, r.KeyHi)},
+		{Gen_VelRange, RangeAmount(r.VelLo, r.VelHi)},
+	}
+
+	offset := func(fine, coarse SFGenerator, v uint32, base uint32) {
+		d := int(v) - int(base)
+		if d == 0 {
+			return
+		}
+		gens = append(gens, Generator{fine, GenAmount(d % 32768)})
+		if d/32768 != 0 {
+			gens = append(gens, Generator{coarse, GenAmount(d / 32768)})
+		}
+	}
+	offset(Gen_StartAddrsOffset, Gen_StartAddrsCoarseOffset, r.Start, 0)
+	offset(Gen_EndAddrsOffset, Gen_EndAddrsCoarseOffset, r.End, s.End-s.Start)
+	offset(Gen_StartloopAddrsOffset, Gen_StartloopAddrsCoarseOffset, r.LoopStart, s.Startloop-s.Start)
+	offset(Gen_EndloopAddrsOffset, Gen_EndloopAddrsCoarseOffset, r.LoopEnd, s.Endloop-s.Start)
+
+	gens = append(gens,
+		Generator{Gen_CoarseTune, GenAmount(clampInt16(a.Tune / 100))},
+		Generator{Gen_FineTune, GenAmount(clampInt16(a.Tune % 100))},
+		Generator{Gen_ScaleTuning, GenAmount(clampInt16(a.ScaleTuning))},
+		Generator{Gen_InitialAttenuation, GenAmount(clampInt16(int(math.Round(a.Attenuation * 10))))},
+		Generator{Gen_Pan, GenAmount(clampInt16(int(math.Round(a.Pan * 500))))},
+		Generator{Gen_InitialFilterFc, GenAmount(hzToAbsoluteCents(a.FilterCutoff))},
+		Generator{Gen_InitialFilterQ, GenAmount(clampInt16(int(math.Round(a.FilterResonance * 10))))},
+		Generator{Gen_ReverbEffectsSend, GenAmount(clampInt16(int(math.Round(a.Reverb * 1000))))},
+		Generator{Gen_ChorusEffectsSend, GenAmount(clampInt16(int(math.Round(a.Chorus * 1000))))},
+		Generator{Gen_SampleModes, GenAmount(a.LoopMode)},
+		Generator{Gen_ModEnvToPitch, GenAmount(clampInt16(a.ModEnvToPitch))},
+		Generator{Gen_ModEnvToFilterFc, GenAmount(clampInt16(a.ModEnvToFilter))},
+		Generator{Gen_ExclusiveClass, GenAmount(clampInt16(a.ExclusiveClass))},
+	)
+	gens = append(gens, envelopeGenerators(a.AmpEnvelope, Gen_DelayVolEnv)...)
+	gens = append(gens, envelopeGenerators(a.ModEnvelope, Gen_DelayModEnv)...)
+	if r.RootKey != s.OriginalPitch {
+		gens = append(gens, Generator{Gen_OverridingRootKey, GenAmount(r.RootKey)})
+	}
+
+	z := Zone{}
+	for _, g := range gens {
+		if g.GenAmount.AsInt16() != GeneratorDefaults[g.GenOper] {
+			z.Generators = append(z.Generators, g)
+		}
+	}
+	return z
+}
+
+// FromModel converts a Model to a SoundFont, each model instrument becoming
+// a preset with a single zone playing an instrument of the same name.
+func FromModel(m *Model) (*SoundFont, error) {
+	l := &Layout{}
+	pool := &samplePool{}
+	for i, ms := range m.Samples {
+		if ms.Link >= len(m.Samples) {
+			return nil, fmt.Errorf("sample %d links to sample %d, out of range", i, ms.Link)
+		}
+		s := SampleHeader{
+			SampleName:      fixedName(ms.Name),
+			Startloop:       ms.LoopStart,
+			Endloop:         ms.LoopEnd,
+			SampleRate:      ms.Rate,
+			OriginalPitch:   ms.RootKey,
+			PitchCorrection: ms.Correction,
+			SampleType:      SampleType_Mono,
+		}
+		switch ms.Channel {
+		case ModelChannel_Left:
+			s.SampleType = SampleType_Left
+		case ModelChannel_Right:
+			s.SampleType = SampleType_Right
+		}
+		if ms.Link >= 0 {
+			s.SampleLink = uint16(ms.Link)
+		}
+		l.Samples = append(l.Samples, pool.add(s, ms.Higher, ms.Lower))
+	}
+
+	for i, inst := range m.Instruments {
+		data := InstrumentData{Name: fixedName(inst.Name)}
+		for _, r := range inst.Regions {
+			if r.Sample < 0 || r.Sample >= len(l.Samples) {
+				return nil, fmt.Errorf("instrument %q plays sample %d, out of range", inst.Name, r.Sample)
+			}
+			z := regionZone(r, l.Samples[r.Sample])
+			z.Generators = append(z.Generators, Generator{Gen_SampleID, GenAmount(r.Sample)})
+			data.Zones = append(data.Zones, z)
+		}
+		l.Instruments = append(l.Instruments, data)
+		l.Presets = append(l.Presets, PresetData{
+			Header: PresetHeader{PresetName: fixedName(inst.Name), Bank: inst.Bank, Preset: inst.Program},
+			Zones:  []Zone{{Generators: []Generator{{Gen_Instrument, GenAmount(i)}}}},
+		})
+	}
+
+	hydra, err := l.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SoundFontInfo{Engine: "EMU8000", Name: m.Name, Software: "sf"}
+	info.SfVersion.Major, info.SfVersion.Minor = 2, 1
+	return &SoundFont{
+		Info:    info,
+		Samples: pool.samples(),
+		Hydra:   hydra,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package sf
+
+// modDestLink is set in ModDestOper when the destination is another modulator
+// rather than a generator. The remaining 15 bits hold the index of that
+// modulator relative to the first modulator in the zone.
+const modDestLink SFGenerator = 0x8000
+
+// isLinkSource reports whether src is the 'link' general controller, which
+// receives the output of a linked modulator, ignoring the direction, polarity
+// and type bits.
+func isLinkSource(src SFModulator) bool {
+	return !src.IsMIDICC() && src.Controller() == ModController_Link
+}
+
+// ModulatorNode is a modulator that survived link resolution.
+type ModulatorNode struct {
+	Modulator
+
+	// Index is the position of the modulator within its zone.
+	Index int
+
+	// Inputs are the modulators whose output is summed into this modulator's
+	// source. Only modulators with a 'link' source have inputs.
+	Inputs []*ModulatorNode
+
+	// Output is the modulator this one feeds, or nil when the destination is a
+	// generator.
+	Output *ModulatorNode
+}
+
+// ModulatorGraph is the resolved modulator DAG of a single zone.
+type ModulatorGraph struct {
+	// Nodes holds every usable modulator, in zone order.
+	Nodes []*ModulatorNode
+
+	// Roots holds the nodes whose destination is a generator. Walking Inputs
+	// from the roots visits the whole graph.
+	Roots []*ModulatorNode
+}
+
+// ResolveModulators builds the modulator DAG for the modulators of one zone.
+//
+// Following the spec, the following modulators are dropped along with every
+// modulator that (transitively) feeds only into them:
+//   - modulators whose ModAmtSrcOper is 'link'
+//   - links pointing past the end of the zone or to a modulator whose source is not 'link'
+//   - modulators that are part of a circular link
+//   - modulators with a 'link' source that no other modulator links to
+func ResolveModulators(mods []Modulator) *ModulatorGraph {
+	// target[i] is the index of the modulator i links to, or -1
+	target := make([]int, len(mods))
+	usable := make([]bool, len(mods))
+	for i, m := range mods {
+		target[i] = -1
+		usable[i] = !isLinkSource(m.ModAmtSrcOper)
+
+		if m.ModDestOper&modDestLink != 0 {
+			t := int(m.ModDestOper &^ modDestLink)
+			if t >= len(mods) || t == i || !isLinkSource(mods[t].ModSrcOper) {
+				usable[i] = false
+				continue
+			}
+			target[i] = t
+		}
+	}
+
+	// A modulator is usable only if following its links reaches a generator
+	// through usable modulators without revisiting itself.
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(mods))
+	var walk func(i int) bool
+	walk = func(i int) bool {
+		switch state[i] {
+		case visiting:
+			// circular link
+			return false
+		case done:
+			return usable[i]
+		}
+
+		state[i] = visiting
+		ok := usable[i]
+		if ok && target[i] >= 0 {
+			ok = walk(target[i])
+		}
+		state[i] = done
+		usable[i] = ok
+		return ok
+	}
+	for i := range mods {
+		walk(i)
+	}
+
+	// Link sources without any usable input carry no signal.
+	for changed := true; changed; {
+		changed = false
+
+		fed := make([]bool, len(mods))
+		for i := range mods {
+			if usable[i] && target[i] >= 0 {
+				fed[target[i]] = true
+			}
+		}
+		for i, m := range mods {
+			if usable[i] && isLinkSource(m.ModSrcOper) && !fed[i] {
+				usable[i] = false
+				changed = true
+			}
+		}
+
+		// dropping a node can strand the modulators feeding into it
+		for i := range mods {
+			if usable[i] && target[i] >= 0 && !usable[target[i]] {
+				usable[i] = false
+				changed = true
+			}
+		}
+	}
+
+	graph := &ModulatorGraph{}
+	nodes := make([]*ModulatorNode, len(mods))
+	for i, m := range mods {
+		if !usable[i] {
+			continue
+		}
+		nodes[i] = &ModulatorNode{Modulator: m, Index: i}
+		graph.Nodes = append(graph.Nodes, nodes[i])
+	}
+
+	for i, n := range nodes {
+		if n == nil {
+			continue
+		}
+		if target[i] < 0 {
+			graph.Roots = append(graph.Roots, n)
+			continue
+		}
+		n.Output = nodes[target[i]]
+		n.Output.Inputs = append(n.Output.Inputs, n)
+	}
+
+	return graph
+}
+
+// ModulatorGraph resolves the links between the zone's modulators.
+func (z Zone) ModulatorGraph() *ModulatorGraph {
+	return ResolveModulators(z.Modulators)
+}
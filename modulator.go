@@ -0,0 +1,134 @@
+package sf
+
+import "fmt"
+
+// The general controllers a modulator source can name when its CC flag is
+// clear, from section 8.2.1 of the specification.
+const (
+	ModController_NoController          uint8 = 0
+	ModController_NoteOnVelocity        uint8 = 2
+	ModController_NoteOnKey             uint8 = 3
+	ModController_PolyPressure          uint8 = 10
+	ModController_ChannelPressure       uint8 = 13
+	ModController_PitchWheel            uint8 = 14
+	ModController_PitchWheelSensitivity uint8 = 16
+	ModController_Link                  uint8 = 127
+)
+
+// generalControllerNames names the general controllers the spec defines.
+var generalControllerNames = map[uint8]string{
+	ModController_NoController:          "noController",
+	ModController_NoteOnVelocity:        "noteOnVelocity",
+	ModController_NoteOnKey:             "noteOnKey",
+	ModController_PolyPressure:          "polyPressure",
+	ModController_ChannelPressure:       "channelPressure",
+	ModController_PitchWheel:            "pitchWheel",
+	ModController_PitchWheelSensitivity: "pitchWheelSensitivity",
+	ModController_Link:                  "link",
+}
+
+// ModDirection is which way a modulator source runs over its controller's
+// range.
+type ModDirection uint8
+
+const (
+	// ModDirection_Positive runs from the minimum at controller value 0 to
+	// the maximum at 127.
+	ModDirection_Positive ModDirection = 0
+	// ModDirection_Negative runs from the maximum down to the minimum.
+	ModDirection_Negative ModDirection = 1
+)
+
+func (d ModDirection) String() string {
+	if d == ModDirection_Negative {
+		return "negative"
+	}
+	return "positive"
+}
+
+// ModPolarity is the range a modulator source maps its controller onto.
+type ModPolarity uint8
+
+const (
+	// ModPolarity_Unipolar maps onto 0 to 1.
+	ModPolarity_Unipolar ModPolarity = 0
+	// ModPolarity_Bipolar maps onto -1 to 1.
+	ModPolarity_Bipolar ModPolarity = 1
+)
+
+func (p ModPolarity) String() string {
+	if p == ModPolarity_Bipolar {
+		return "bipolar"
+	}
+	return "unipolar"
+}
+
+// ModSourceType is the curve a modulator source follows.
+type ModSourceType uint8
+
+const (
+	ModSourceType_Linear ModSourceType = 0
+	// ModSourceType_Concave follows the spec's concave curve, the shape of
+	// the velocity to attenuation default modulator.
+	ModSourceType_Concave ModSourceType = 1
+	ModSourceType_Convex  ModSourceType = 2
+	// ModSourceType_Switch is the minimum below the controller's midpoint and
+	// the maximum from it on.
+	ModSourceType_Switch ModSourceType = 3
+)
+
+func (t ModSourceType) String() string {
+	switch t {
+	case ModSourceType_Linear:
+		return "linear"
+	case ModSourceType_Concave:
+		return "concave"
+	case ModSourceType_Convex:
+		return "convex"
+	case ModSourceType_Switch:
+		return "switch"
+	}
+	return fmt.Sprintf("type%d", uint8(t))
+}
+
+// Controller returns the index of the source's controller: a MIDI CC number
+// when IsMIDICC is set, else one of the ModController_ general controllers.
+func (m SFModulator) Controller() uint8 {
+	return uint8(m & 0x7f)
+}
+
+// IsMIDICC reports whether Controller is a MIDI continuous controller number
+// rather than a general controller.
+func (m SFModulator) IsMIDICC() bool {
+	return m&0x80 != 0
+}
+
+// Direction returns which way the source runs.
+func (m SFModulator) Direction() ModDirection {
+	return ModDirection(m >> 8 & 1)
+}
+
+// Polarity returns whether the source is unipolar or bipolar.
+func (m SFModulator) Polarity() ModPolarity {
+	return ModPolarity(m >> 9 & 1)
+}
+
+// Type returns the source's curve. The spec defines types 0 to 3, a source
+// of any other type is to be ignored.
+func (m SFModulator) Type() ModSourceType {
+	return ModSourceType(m >> 10)
+}
+
+// String describes the source, such as "CC7 negative unipolar concave" or
+// "noteOnVelocity positive unipolar linear".
+func (m SFModulator) String() string {
+	controller := fmt.Sprintf("CC%d", m.Controller())
+	if !m.IsMIDICC() {
+		name, ok := generalControllerNames[m.Controller()]
+		if !ok {
+			name = fmt.Sprintf("general%d", m.Controller())
+		}
+		controller = name
+	}
+	return fmt.Sprintf("%s %v %v %v", controller, m.Direction(), m.Polarity(), m.Type())
+}
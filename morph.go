@@ -0,0 +1,163 @@
+package sf
+
+import (
+	"fmt"
+	"math"
+)
+
+// morphTag marks the Morphology field of a preset made by MorphPresets: the
+// top byte is 'M' and the low bits hold the morph amount in thousandths.
+const morphTag = 'M' << 24
+
+// morphable reports whether a generator is a continuous parameter that can
+// be interpolated. Its units are logarithmic where that matters (timecents,
+// absolute cents, centibels), so linear steps sound even.
+func morphable(op SFGenerator) bool {
+	switch op {
+	case Gen_ModLfoToPitch, Gen_VibLfoToPitch, Gen_ModEnvToPitch,
+		Gen_InitialFilterFc, Gen_InitialFilterQ, Gen_ModLfoToFilterFc, Gen_ModEnvToFilterFc,
+		Gen_ModLfoToVolume, Gen_ChorusEffectsSend, Gen_ReverbEffectsSend, Gen_Pan,
+		Gen_DelayModLFO, Gen_FreqModLFO, Gen_DelayVibLFO, Gen_FreqVibLFO,
+		Gen_DelayModEnv, Gen_AttackModEnv, Gen_HoldModEnv, Gen_DecayModEnv, Gen_SustainModEnv, Gen_ReleaseModEnv,
+		Gen_KeynumToModEnvHold, Gen_KeynumToModEnvDecay,
+		Gen_DelayVolEnv, Gen_AttackVolEnv, Gen_HoldVolEnv, Gen_DecayVolEnv, Gen_SustainVolEnv, Gen_ReleaseVolEnv,
+		Gen_KeynumToVolEnvHold, Gen_KeynumToVolEnvDecay,
+		Gen_InitialAttenuation, Gen_FineTune:
+		return true
+	}
+	return false
+}
+
+// MorphAmount returns how far toward its second source a preset made by
+// MorphPresets was morphed, and false for any other preset.
+func MorphAmount(p PresetHeader) (float64, bool) {
+	if p.Morphology&0xff000000 != morphTag {
+		return 0, false
+	}
+	return float64(p.Morphology&0xffff) / 1000, true
+}
+
+// freeSlot moves a copy of a preset's header to the first bank, from its own
+// up and skipping the percussion bank, where its program is not taken. A
+// percussion kit, in bank 128 or above, stays in its bank and moves to the
+// next free program there instead, wrapping around past 127: moved to another
+// bank it would no longer play as percussion.
+func freeSlot(l *Layout, header PresetHeader) (PresetHeader, error) {
+	used := map[[2]uint16]bool{}
+	for _, p := range l.Presets {
+		used[[2]uint16{p.Header.Bank, p.Header.Preset}] = true
+	}
+
+	if header.Bank >= 128 {
+		for i := 0; i < 128; i++ {
+			program := (header.Preset + uint16(i)) % 128
+			if !used[[2]uint16{header.Bank, program}] {
+				header.Preset = program
+				return header, nil
+			}
+		}
+		return header, fmt.Errorf("no free program in percussion bank %d", header.Bank)
+	}
+
+	for used[[2]uint16{header.Bank, header.Preset}] {
+		header.Bank++
+		if header.Bank == 128 {
+			header.Bank++
+		}
+		if header.Bank > 16383 {
+			return header, fmt.Errorf("no free bank for program %d", header.Preset)
+		}
+	}
+	return header, nil
+}
+
+// MorphPresets adds an experimental hybrid of presets a and b, indices into
+// Headers, and returns the new preset's index. The hybrid plays a's samples
+// with a's key and velocity layout; the continuous parameters of each region
+// (envelopes, filter, attenuation, pan, LFOs, effect sends) move a fraction t
+// of the way toward those of the region b plays for the same note. Regions b
+// has no match for keep a's values.
+//
+// The hybrid is one new preset playing one new instrument with a zone per
+// region of a. It takes a's name and program in the first bank above a's
+// where that program is free, or at the next free program of a's bank when a
+// is a percussion kit, and is tagged in its Morphology field, see
+// MorphAmount.
+func (h *SoundFontHydra) MorphPresets(a, b int, t float64) (int, error) {
+	if t < 0 || t > 1 {
+		return 0, fmt.Errorf("morph amount %g is outside 0 to 1", t)
+	}
+	regions, err := h.PresetRegions(a)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := h.PresetZones(b); err != nil {
+		return 0, err
+	}
+
+	l, err := h.Unpack()
+	if err != nil {
+		return 0, err
+	}
+
+	inst := InstrumentData{Name: fixedName("Morph " + trimName(h.Headers[a].PresetName))}
+	for _, ra := range regions {
+		keyLo, keyHi := rangeBytes(ra.Gen(Gen_KeyRange))
+		velLo, velHi := rangeBytes(ra.Gen(Gen_VelRange))
+		values := ra.Generators
+
+		matches, err := h.Regions(b, uint8((int(keyLo)+int(keyHi))/2), uint8((int(velLo)+int(velHi))/2))
+		if err != nil {
+			return 0, err
+		}
+		if len(matches) > 0 {
+			for op := SFGenerator(0); op < Gen_EndOper; op++ {
+				if morphable(op) {
+					v := (1-t)*float64(values[op]) + t*float64(matches[0].Generators[op])
+					values[op] = clampInt16(int(math.Round(v)))
+				}
+			}
+		}
+
+		z := Zone{Generators: []Generator{{Gen_KeyRange, GenAmount(values[Gen_KeyRange])}, {Gen_VelRange, GenAmount(values[Gen_VelRange])}}}
+		for op := SFGenerator(0); op < Gen_EndOper; op++ {
+			switch op {
+			case Gen_KeyRange, Gen_VelRange, Gen_Instrument, Gen_SampleID:
+				continue
+			}
+			if values[op] != GeneratorDefaults[op] {
+				z.Generators = append(z.Generators, Generator{op, GenAmount(values[op])})
+			}
+		}
+		z.Generators = append(z.Generators, Generator{Gen_SampleID, GenAmount(ra.SampleIndex)})
+
+	mods:
+		for _, m := range ra.Modulators {
+			for _, d := range DefaultModulators {
+				if m == d {
+					continue mods
+				}
+			}
+			z.Modulators = append(z.Modulators, m)
+		}
+		inst.Zones = append(inst.Zones, z)
+	}
+
+	header, err := freeSlot(l, h.Headers[a])
+	if err != nil {
+		return 0, err
+	}
+	header.Morphology = morphTag | uint32(math.Round(t*1000))
+
+	l.Instruments = append(l.Instruments, inst)
+	l.Presets = append(l.Presets, PresetData{
+		Header: header,
+		Zones:  []Zone{{Generators: []Generator{{Gen_Instrument, GenAmount(len(l.Instruments) - 1)}}}},
+	})
+	packed, err := l.Pack()
+	if err != nil {
+		return 0, err
+	}
+	*h = *packed
+	return len(l.Presets) - 1, nil
+}
@@ -0,0 +1,159 @@
+package sf
+
+import "sort"
+
+// OptimizeStats reports what Optimize removed.
+type OptimizeStats struct {
+	GeneratorsRemoved int
+	ZonesMerged       int
+}
+
+// zoneKeyRange returns a zone's key range and whether it sets one.
+func zoneKeyRange(z Zone) (lo, hi uint8, ok bool) {
+	for _, g := range z.Generators {
+		if g.GenOper == Gen_KeyRange {
+			lo, hi = g.GenAmount.AsRange()
+			return lo, hi, true
+		}
+	}
+	return 0, 127, false
+}
+
+// zoneSignature identifies a zone by its generators and modulators, ignoring
+// its key range. Generators must not repeat.
+func zoneSignature(z Zone) string {
+	gens := make([]Generator, 0, len(z.Generators))
+	for _, g := range z.Generators {
+		if g.GenOper != Gen_KeyRange {
+			gens = append(gens, g)
+		}
+	}
+	sort.Slice(gens, func(i, j int) bool { return gens[i].GenOper < gens[j].GenOper })
+
+	buf := make([]byte, 0, 4*len(gens)+10*len(z.Modulators)+1)
+	for _, g := range gens {
+		buf = append(buf, byte(g.GenOper), byte(g.GenOper>>8), byte(g.GenAmount), byte(uint16(g.GenAmount)>>8))
+	}
+	buf = append(buf, 0xff)
+	for _, m := range z.Modulators {
+		for _, v := range []uint16{uint16(m.ModSrcOper), uint16(m.ModDestOper), uint16(m.ModAmount), uint16(m.ModAmtSrcOper), uint16(m.ModTransOper)} {
+			buf = append(buf, byte(v), byte(v>>8))
+		}
+	}
+	return string(buf)
+}
+
+// mergeAdjacentZones merges local zones that differ only in key ranges that
+// touch end to end, such as a sample mapped to 36-47 and again to 48-59, into
+// one zone covering both. Overlapping zones are left alone since both sound
+// where they overlap. The merged zone takes the place of the first of them.
+// It returns the zones and how many were merged away. Generators must not
+// repeat within a zone.
+func mergeAdjacentZones(zones []Zone, terminal SFGenerator) ([]Zone, int) {
+	type member struct {
+		index  int
+		lo, hi uint8
+	}
+	groups := map[string][]member{}
+	var order []string
+	for i, z := range zones {
+		if _, ok := z.terminal(terminal); !ok {
+			continue
+		}
+		lo, hi, ok := zoneKeyRange(z)
+		if !ok {
+			continue
+		}
+		sig := zoneSignature(z)
+		if groups[sig] == nil {
+			order = append(order, sig)
+		}
+		groups[sig] = append(groups[sig], member{i, lo, hi})
+	}
+
+	removed := make([]bool, len(zones))
+	merged := 0
+	for _, sig := range order {
+		members := groups[sig]
+		sort.Slice(members, func(i, j int) bool { return members[i].lo < members[j].lo })
+
+		for start := 0; start < len(members); {
+			end := start + 1
+			for end < len(members) && int(members[end-1].hi)+1 == int(members[end].lo) {
+				end++
+			}
+			if end-start > 1 {
+				run := members[start:end]
+				keep := run[0].index
+				for _, m := range run[1:] {
+					if m.index < keep {
+						keep = m.index
+					}
+				}
+				for _, m := range run {
+					if m.index != keep {
+						removed[m.index] = true
+						merged++
+					}
+				}
+				for k, g := range zones[keep].Generators {
+					if g.GenOper == Gen_KeyRange {
+						zones[keep].Generators[k].GenAmount = RangeAmount(run[0].lo, run[len(run)-1].hi)
+					}
+				}
+			}
+			start = end
+		}
+	}
+
+	out := zones[:0]
+	for i, z := range zones {
+		if !removed[i] {
+			out = append(out, z)
+		}
+	}
+	return out, merged
+}
+
+// Optimize shrinks the generator tables without changing how the bank plays:
+// redundant generators are removed as in Canonicalize, and zones that differ
+// only by adjacent key ranges are merged. Unlike Canonicalize it leaves the
+// presets in their original order.
+func (h *SoundFontHydra) Optimize() (OptimizeStats, error) {
+	var stats OptimizeStats
+	l, err := h.Unpack()
+	if err != nil {
+		return stats, err
+	}
+
+	optimize := func(zones []Zone, terminal SFGenerator) []Zone {
+		before := 0
+		for _, z := range zones {
+			before += len(z.Generators)
+		}
+		zones = removeRedundantGenerators(zones, terminal)
+		after := 0
+		for _, z := range zones {
+			after += len(z.Generators)
+		}
+		stats.GeneratorsRemoved += before - after
+
+		zones, merged := mergeAdjacentZones(zones, terminal)
+		stats.ZonesMerged += merged
+		return zones
+	}
+
+	for i := range l.Presets {
+		l.Presets[i].Zones = optimize(l.Presets[i].Zones, Gen_Instrument)
+	}
+	for i := range l.Instruments {
+		l.Instruments[i].Zones = optimize(l.Instruments[i].Zones, Gen_SampleID)
+	}
+
+	packed, err := l.Pack()
+	if err != nil {
+		return stats, err
+	}
+	*h = *packed
+	return stats, nil
+}
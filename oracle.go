@@ -0,0 +1,125 @@
+package sf
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Oracle holds the specification's numbers for the generator subsystem, as
+// data rather than code, so that changes to the generator tables and unit
+// conversions can be checked against an independent transcription.
+type Oracle struct {
+	Spec        string             `json:"spec"`
+	Generators  []OracleGenerator  `json:"generators"`
+	Conversions []OracleConversion `json:"conversions"`
+}
+
+// OracleGenerator is one row of the spec's generator summary.
+type OracleGenerator struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	Unit   string `json:"unit"`
+	// Min and Max are nil when the spec gives no bound.
+	Min     *int  `json:"min,omitempty"`
+	Max     *int  `json:"max,omitempty"`
+	Default int16 `json:"default"`
+	// Preset and Instrument tell at which levels the generator is legal.
+	Preset     bool `json:"preset"`
+	Instrument bool `json:"instrument"`
+}
+
+// OracleConversion is a sample point of a unit conversion: formula applied
+// to Input gives Output. Formulas are "timecents" (to seconds),
+// "absoluteCents" (to Hz) and "centibels" (to linear gain).
+type OracleConversion struct {
+	Formula string  `json:"formula"`
+	Input   float64 `json:"input"`
+	Output  float64 `json:"output"`
+}
+
+//go:embed oracle/generators.json
+var oracleData []byte
+
+// SpecOracle returns the oracle shipped with the package, transcribed from
+// the SoundFont 2.04 specification.
+func SpecOracle() *Oracle {
+	o, err := LoadOracle(bytes.NewReader(oracleData))
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// LoadOracle reads an oracle in the format of oracle/generators.json.
+func LoadOracle(r io.Reader) (*Oracle, error) {
+	o := &Oracle{}
+	if err := json.NewDecoder(r).Decode(o); err != nil {
+		return nil, fmt.Errorf("oracle: %w", err)
+	}
+	return o, nil
+}
+
+// Check compares the package's generator tables and conversions with the
+// oracle and returns every disagreement.
+func (o *Oracle) Check() []Problem {
+	var problems []Problem
+	add := func(rule, where, format string, args ...interface{}) {
+		problems = append(problems, Problem{rule, where, fmt.Sprintf(format, args...)})
+	}
+
+	for _, g := range o.Generators {
+		op := SFGenerator(g.Number)
+		where := fmt.Sprintf("generator %d (%s)", g.Number, g.Name)
+		if op >= Gen_EndOper {
+			add("oracle-generator", where, "not known to the package")
+			continue
+		}
+		if name := op.String(); name != g.Name {
+			add("oracle-name", where, "named %q", name)
+		}
+		if def := GeneratorDefaults[op]; def != g.Default {
+			add("oracle-default", where, "defaults to %d, the spec says %d", def, g.Default)
+		}
+		if PresetLegal(op) != g.Preset {
+			add("oracle-legality", where, "PresetLegal is %v, the spec says %v", PresetLegal(op), g.Preset)
+		}
+		if InstrumentLegal(op) != g.Instrument {
+			add("oracle-legality", where, "InstrumentLegal is %v, the spec says %v", InstrumentLegal(op), g.Instrument)
+		}
+	}
+
+	for _, c := range o.Conversions {
+		var got float64
+		switch c.Formula {
+		case "timecents":
+			got = timecentsToSeconds(int(c.Input))
+		case "absoluteCents":
+			got = absoluteCentsToHz(int(c.Input))
+		case "centibels":
+			got = centibelsToGain(c.Input)
+		default:
+			add("oracle-conversion", c.Formula, "unknown formula")
+			continue
+		}
+		if math.Abs(got-c.Output) > 1e-6*math.Max(1, math.Abs(c.Output)) {
+			add("oracle-conversion", fmt.Sprintf("%s(%g)", c.Formula, c.Input), "gives %g, the spec says %g", got, c.Output)
+		}
+	}
+
+	return problems
+}
+
+// Range returns the generator's bounds from the oracle, and false when the
+// oracle has no row for op.
+func (o *Oracle) Range(op SFGenerator) (min, max *int, ok bool) {
+	for _, g := range o.Generators {
+		if g.Number == int(op) {
+			return g.Min, g.Max, true
+		}
+	}
+	return nil, nil, false
+}
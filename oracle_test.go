@@ -0,0 +1,10 @@
+package sf
+
+import "testing"
+
+// TestSpecOracle checks the embedded spec tables agree with the library.
+func TestSpecOracle(t *testing.T) {
+	for _, p := range SpecOracle().Check() {
+		t.Errorf("%s: %s: %s", p.Rule, p.Where, p.Message)
+	}
+}
@@ -0,0 +1,79 @@
+package sf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// generatorRank orders generators within a zone: keyRange must come first,
+// velRange may only be preceded by keyRange, and the instrument or sampleID
+// terminal must come last.
+func generatorRank(op SFGenerator) int {
+	switch op {
+	case Gen_KeyRange:
+		return 0
+	case Gen_VelRange:
+		return 1
+	case Gen_Instrument, Gen_SampleID:
+		return 3
+	}
+	return 2
+}
+
+// validateOrder reports keyRange and velRange generators out of place, and an
+// instrument or sampleID terminal that is not the zone's last generator.
+func validateOrder(where string, zone Zone) []Problem {
+	var problems []Problem
+	for i, g := range zone.Generators {
+		switch g.GenOper {
+		case Gen_Instrument, Gen_SampleID:
+			if i != len(zone.Generators)-1 {
+				problems = append(problems, Problem{"bad-generator-order", where, fmt.Sprintf("%v is generator %d of %d, it must be last", g.GenOper, i, len(zone.Generators))})
+			}
+		case Gen_KeyRange:
+			if i != 0 {
+				problems = append(problems, Problem{"bad-generator-order", where, fmt.Sprintf("keyRange is generator %d, it must be first", i)})
+			}
+		case Gen_VelRange:
+			if i > 1 || (i == 1 && zone.Generators[0].GenOper != Gen_KeyRange) {
+				problems = append(problems, Problem{"bad-generator-order", where, fmt.Sprintf("velRange is generator %d, it may only be preceded by keyRange", i)})
+			}
+		}
+	}
+	return problems
+}
+
+// orderGenerators stably sorts a zone's generators into the order the spec
+// requires.
+func orderGenerators(gens []Generator) {
+	sort.SliceStable(gens, func(i, j int) bool {
+		return generatorRank(gens[i].GenOper) < generatorRank(gens[j].GenOper)
+	})
+}
+
+// ReorderGenerators puts the generators of every zone into the order the spec
+// requires, in place. Only range generators and terminals move, everything
+// else keeps its relative order.
+func (h *SoundFontHydra) ReorderGenerators() error {
+	for i := 0; i+1 < len(h.Headers); i++ {
+		zones, err := h.PresetZones(i)
+		if err != nil {
+			return err
+		}
+		for _, zone := range zones {
+			orderGenerators(zone.Generators)
+		}
+	}
+
+	for i := 0; i+1 < len(h.Instuments); i++ {
+		zones, err := h.InstrumentZones(i)
+		if err != nil {
+			return err
+		}
+		for _, zone := range zones {
+			orderGenerators(zone.Generators)
+		}
+	}
+
+	return nil
+}
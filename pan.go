@@ -0,0 +1,63 @@
+package sf
+
+import "math"
+
+// PanLaw is how a pan position splits a voice between the left and right
+// channels, named for how far each channel drops at the center. Banks are
+// voiced assuming one law or another, so a bank mixed with the wrong law
+// sounds louder or quieter in the middle than its author heard it.
+type PanLaw int
+
+const (
+	// PanLaw_3dB keeps the power constant across the field, sin and cos
+	// gains, as most synthesizers do.
+	PanLaw_3dB PanLaw = iota
+	// PanLaw_4_5dB is halfway between the other two, the geometric mean of
+	// their gains.
+	PanLaw_4_5dB
+	// PanLaw_6dB keeps the amplitude constant, linear gains, so a centered
+	// mono voice sums back to full level.
+	PanLaw_6dB
+)
+
+func (l PanLaw) String() string {
+	switch l {
+	case PanLaw_3dB:
+		return "-3 dB"
+	case PanLaw_4_5dB:
+		return "-4.5 dB"
+	case PanLaw_6dB:
+		return "-6 dB"
+	}
+	return "unknown"
+}
+
+// gain returns a channel's gain for a position x from 0 (away from it) to 1
+// (fully toward it).
+func (l PanLaw) gain(x float64) float64 {
+	switch l {
+	case PanLaw_4_5dB:
+		return math.Sqrt(x * math.Sin(x*math.Pi/2))
+	case PanLaw_6dB:
+		return x
+	}
+	return math.Sin(x * math.Pi / 2)
+}
+
+// Gains returns the left and right channel gains for a pan generator amount,
+// in tenths of a percent from -500 (left) to 500 (right). width scales the
+// position about the center before the law is applied: 0 collapses every
+// voice to the middle, 1 plays pans as authored and more spreads them
+// further, up to the hard left and right. Narrowing the width brings the
+// left and right samples of a stereo pair together.
+func (l PanLaw) Gains(pan int16, width float64) (left, right float64) {
+	p := math.Max(-500, math.Min(500, float64(pan)*math.Max(width, 0)))
+	x := (p + 500) / 1000
+	return l.gain(1 - x), l.gain(x)
+}
+
+// PanGains returns the region's left and right channel gains under law, see
+// PanLaw.Gains.
+func (r *Region) PanGains(law PanLaw, width float64) (left, right float64) {
+	return law.Gains(r.Gen(Gen_Pan), width)
+}
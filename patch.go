@@ -0,0 +1,212 @@
+package sf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Patch is the difference between two SoundFonts, compact enough to exchange
+// instead of whole files.
+type Patch struct {
+	// Info replaces the INFO data, nil when it is unchanged.
+	Info *SoundFontInfo
+
+	// Hydra holds the hydra tables that changed, each replaced as a whole.
+	// Every table in a valid file ends with a terminal record, so a nil table
+	// means unchanged.
+	Hydra SoundFontHydra
+
+	// SamplesHigher and SamplesLower splice the sample data, nil when it is
+	// unchanged.
+	SamplesHigher *Int16Splice
+	SamplesLower  *Int8Splice
+}
+
+// Int16Splice replaces Delete data points starting at Index with Insert.
+type Int16Splice struct {
+	Index, Delete int
+	Insert        []int16
+}
+
+// Int8Splice replaces Delete data points starting at Index with Insert.
+type Int8Splice struct {
+	Index, Delete int
+	Insert        []int8
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p Patch) IsEmpty() bool {
+	return p.Info == nil && reflect.DeepEqual(p.Hydra, SoundFontHydra{}) &&
+		p.SamplesHigher == nil && p.SamplesLower == nil
+}
+
+// diffInt16 returns the single splice turning a into b, trimming their common
+// prefix and suffix, or nil when they are equal.
+func diffInt16(a, b []int16) *Int16Splice {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return nil
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return &Int16Splice{
+		Index:  prefix,
+		Delete: len(a) - prefix - suffix,
+		Insert: append([]int16(nil), b[prefix:len(b)-suffix]...),
+	}
+}
+
+// diffInt8 returns the single splice turning a into b, trimming their common
+// prefix and suffix, or nil when they are equal.
+func diffInt8(a, b []int8) *Int8Splice {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return nil
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return &Int8Splice{
+		Index:  prefix,
+		Delete: len(a) - prefix - suffix,
+		Insert: append([]int8(nil), b[prefix:len(b)-suffix]...),
+	}
+}
+
+// CreatePatch returns the patch that turns old into new. Changed hydra tables
+// are shared with new, not copied.
+func CreatePatch(old, new *SoundFont) Patch {
+	var p Patch
+
+	if !reflect.DeepEqual(old.Info, new.Info) && new.Info != nil {
+		info := *new.Info
+		p.Info = &info
+	}
+
+	oh, nh := old.Hydra, new.Hydra
+	if oh == nil {
+		oh = &SoundFontHydra{}
+	}
+	if nh != nil {
+		if !reflect.DeepEqual(oh.Headers, nh.Headers) {
+			p.Hydra.Headers = nh.Headers
+		}
+		if !reflect.DeepEqual(oh.PBag, nh.PBag) {
+			p.Hydra.PBag = nh.PBag
+		}
+		if !reflect.DeepEqual(oh.PresetModulators, nh.PresetModulators) {
+			p.Hydra.PresetModulators = nh.PresetModulators
+		}
+		if !reflect.DeepEqual(oh.PresetGenerators, nh.PresetGenerators) {
+			p.Hydra.PresetGenerators = nh.PresetGenerators
+		}
+		if !reflect.DeepEqual(oh.Instuments, nh.Instuments) {
+			p.Hydra.Instuments = nh.Instuments
+		}
+		if !reflect.DeepEqual(oh.IBag, nh.IBag) {
+			p.Hydra.IBag = nh.IBag
+		}
+		if !reflect.DeepEqual(oh.InstrumentModulators, nh.InstrumentModulators) {
+			p.Hydra.InstrumentModulators = nh.InstrumentModulators
+		}
+		if !reflect.DeepEqual(oh.InstrumentGenerators, nh.InstrumentGenerators) {
+			p.Hydra.InstrumentGenerators = nh.InstrumentGenerators
+		}
+		if !reflect.DeepEqual(oh.Samples, nh.Samples) {
+			p.Hydra.Samples = nh.Samples
+		}
+	}
+
+	var oldSamples, newSamples SoundFontSamples
+	if old.Samples != nil {
+		oldSamples = *old.Samples
+	}
+	if new.Samples != nil {
+		newSamples = *new.Samples
+	}
+	p.SamplesHigher = diffInt16(oldSamples.SamplesHigher, newSamples.SamplesHigher)
+	p.SamplesLower = diffInt8(oldSamples.SamplesLower, newSamples.SamplesLower)
+
+	return p
+}
+
+// ApplyPatch applies p to sf in place. The sample splices are checked against
+// the current sample data before anything is modified. The tables sf gets are
+// copies, so one patch can be applied to several banks, and editing a patched
+// bank leaves the patch and the bank it was created from alone.
+func ApplyPatch(sf *SoundFont, p Patch) error {
+	if sf.Samples == nil {
+		sf.Samples = &SoundFontSamples{}
+	}
+	if s := p.SamplesHigher; s != nil && (s.Index < 0 || s.Delete < 0 || s.Index+s.Delete > len(sf.Samples.SamplesHigher)) {
+		return fmt.Errorf("sample splice %d+%d does not fit %d data points", s.Index, s.Delete, len(sf.Samples.SamplesHigher))
+	}
+	if s := p.SamplesLower; s != nil && (s.Index < 0 || s.Delete < 0 || s.Index+s.Delete > len(sf.Samples.SamplesLower)) {
+		return fmt.Errorf("24-bit sample splice %d+%d does not fit %d data points", s.Index, s.Delete, len(sf.Samples.SamplesLower))
+	}
+
+	if p.Info != nil {
+		info := *p.Info
+		sf.Info = &info
+	}
+
+	if sf.Hydra == nil {
+		sf.Hydra = &SoundFontHydra{}
+	}
+	h := sf.Hydra
+	if p.Hydra.Headers != nil {
+		h.Headers = append(p.Hydra.Headers[:0:0], p.Hydra.Headers...)
+	}
+	if p.Hydra.PBag != nil {
+		h.PBag = append(p.Hydra.PBag[:0:0], p.Hydra.PBag...)
+	}
+	if p.Hydra.PresetModulators != nil {
+		h.PresetModulators = append(p.Hydra.PresetModulators[:0:0], p.Hydra.PresetModulators...)
+	}
+	if p.Hydra.PresetGenerators != nil {
+		h.PresetGenerators = append(p.Hydra.PresetGenerators[:0:0], p.Hydra.PresetGenerators...)
+	}
+	if p.Hydra.Instuments != nil {
+		h.Instuments = append(p.Hydra.Instuments[:0:0], p.Hydra.Instuments...)
+	}
+	if p.Hydra.IBag != nil {
+		h.IBag = append(p.Hydra.IBag[:0:0], p.Hydra.IBag...)
+	}
+	if p.Hydra.InstrumentModulators != nil {
+		h.InstrumentModulators = append(p.Hydra.InstrumentModulators[:0:0], p.Hydra.InstrumentModulators...)
+	}
+	if p.Hydra.InstrumentGenerators != nil {
+		h.InstrumentGenerators = append(p.Hydra.InstrumentGenerators[:0:0], p.Hydra.InstrumentGenerators...)
+	}
+	if p.Hydra.Samples != nil {
+		h.Samples = append(p.Hydra.Samples[:0:0], p.Hydra.Samples...)
+	}
+
+	if s := p.SamplesHigher; s != nil {
+		data := sf.Samples.SamplesHigher
+		out := make([]int16, 0, len(data)-s.Delete+len(s.Insert))
+		out = append(out, data[:s.Index]...)
+		out = append(out, s.Insert...)
+		out = append(out, data[s.Index+s.Delete:]...)
+		sf.Samples.SamplesHigher = out
+	}
+	if s := p.SamplesLower; s != nil {
+		data := sf.Samples.SamplesLower
+		out := make([]int8, 0, len(data)-s.Delete+len(s.Insert))
+		out = append(out, data[:s.Index]...)
+		out = append(out, s.Insert...)
+		out = append(out, data[s.Index+s.Delete:]...)
+		sf.Samples.SamplesLower = out
+	}
+
+	return nil
+}
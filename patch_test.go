@@ -0,0 +1,60 @@
+package sf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	old := GenerateSineBank(3)
+	new := editBank(t, GenerateSineBank(3), func(l *Layout) { setGen(l, "Sine 1", Gen_Pan, 200) })
+	new.Info.Name = "Patched"
+	new.Samples.SamplesHigher[10]++
+
+	p := CreatePatch(old, new)
+	if p.IsEmpty() {
+		t.Fatal("patch between different banks is empty")
+	}
+	if p.Hydra.Headers != nil || p.Hydra.Samples != nil {
+		t.Error("patch replaces hydra tables that did not change")
+	}
+
+	bank := GenerateSineBank(3)
+	if err := ApplyPatch(bank, p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bank.Info, new.Info) {
+		t.Error("INFO differs after the patch")
+	}
+	if !reflect.DeepEqual(bank.Hydra, new.Hydra) {
+		t.Error("hydra differs after the patch")
+	}
+	if !reflect.DeepEqual(bank.Samples, new.Samples) {
+		t.Error("sample data differs after the patch")
+	}
+	if !CreatePatch(bank, new).IsEmpty() {
+		t.Error("patched bank still differs from the new one")
+	}
+
+	// the patched bank does not share tables with the patch or new
+	bank.Hydra.InstrumentGenerators[0].GenAmount++
+	if p.Hydra.InstrumentGenerators[0] == bank.Hydra.InstrumentGenerators[0] || new.Hydra.InstrumentGenerators[0] == bank.Hydra.InstrumentGenerators[0] {
+		t.Error("editing the patched bank changed the patch")
+	}
+}
+
+func TestPatchWrongBase(t *testing.T) {
+	old := GenerateSineBank(3)
+	new := GenerateSineBank(3)
+	new.Samples.SamplesHigher = new.Samples.SamplesHigher[:len(new.Samples.SamplesHigher)-10]
+	p := CreatePatch(old, new)
+
+	// a smaller bank has too few data points for the splice
+	bank := GenerateSineBank(1)
+	if err := ApplyPatch(bank, p); err == nil {
+		t.Fatal("applied a patch to a bank it was not made from")
+	}
+	if !reflect.DeepEqual(bank, GenerateSineBank(1)) {
+		t.Error("a failed patch modified the bank")
+	}
+}
@@ -0,0 +1,48 @@
+package sf
+
+import "math"
+
+// RootKey returns the MIDI key at which the region's sample plays back at its
+// recorded pitch. overridingRootKey takes precedence over the sample header's
+// OriginalPitch, and illegal or unpitched (255) original pitches fall back to
+// 60 as the spec requires.
+func (r *Region) RootKey() int {
+	if k := r.Gen(Gen_OverridingRootKey); k >= 0 && k <= 127 {
+		return int(k)
+	}
+	if r.Sample == nil || r.Sample.OriginalPitch > 127 {
+		return 60
+	}
+	return int(r.Sample.OriginalPitch)
+}
+
+// PitchCents returns how far, in cents, key plays the region's sample away
+// from its recorded pitch. It combines the root key, scaleTuning (cents per
+// key, 100 being equal temperament), coarseTune, fineTune and the sample's
+// PitchCorrection.
+func PitchCents(r *Region, key uint8) float64 {
+	cents := float64(int(key)-r.RootKey()) * float64(r.Gen(Gen_ScaleTuning))
+	cents += float64(r.Gen(Gen_CoarseTune)) * 100
+	cents += float64(r.Gen(Gen_FineTune))
+	if r.Sample != nil {
+		cents += float64(r.Sample.PitchCorrection)
+	}
+	return cents
+}
+
+// PitchRatio returns the factor by which the region's sample is sped up (or
+// slowed down) when key is played. It does not include the conversion from the
+// sample's rate to the output rate, see PhaseIncrement.
+func PitchRatio(r *Region, key uint8) float64 {
+	return math.Pow(2, PitchCents(r, key)/1200)
+}
+
+// PhaseIncrement returns how many sample data points to advance per output
+// frame when key is played at outputRate hertz.
+func PhaseIncrement(r *Region, key uint8, outputRate float64) float64 {
+	ratio := PitchRatio(r, key)
+	if r.Sample == nil || r.Sample.SampleRate == 0 || outputRate <= 0 {
+		return ratio
+	}
+	return ratio * float64(r.Sample.SampleRate) / outputRate
+}
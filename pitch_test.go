@@ -0,0 +1,74 @@
+package sf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		gens   map[SFGenerator]int16
+		sample *SampleHeader
+		key    uint8
+		cents  float64
+	}{
+		{"root key", nil, &SampleHeader{OriginalPitch: 60}, 60, 0},
+		{"octave up", nil, &SampleHeader{OriginalPitch: 60}, 72, 1200},
+		{"below root", nil, &SampleHeader{OriginalPitch: 69}, 57, -1200},
+		{"unpitched sample", nil, &SampleHeader{OriginalPitch: 255}, 61, 100},
+		{"no sample", nil, nil, 62, 200},
+		{"overridingRootKey", map[SFGenerator]int16{Gen_OverridingRootKey: 48}, &SampleHeader{OriginalPitch: 60}, 60, 1200},
+		{"illegal overridingRootKey", map[SFGenerator]int16{Gen_OverridingRootKey: 200}, &SampleHeader{OriginalPitch: 60}, 60, 0},
+		{"coarseTune", map[SFGenerator]int16{Gen_CoarseTune: -3}, &SampleHeader{OriginalPitch: 60}, 60, -300},
+		{"fineTune", map[SFGenerator]int16{Gen_FineTune: 25}, &SampleHeader{OriginalPitch: 60}, 60, 25},
+		{"coarse and fine", map[SFGenerator]int16{Gen_CoarseTune: 2, Gen_FineTune: -50}, &SampleHeader{OriginalPitch: 60}, 64, 550},
+		{"scaleTuning 0", map[SFGenerator]int16{Gen_ScaleTuning: 0}, &SampleHeader{OriginalPitch: 60}, 84, 0},
+		{"scaleTuning 50", map[SFGenerator]int16{Gen_ScaleTuning: 50}, &SampleHeader{OriginalPitch: 60}, 84, 1200},
+		{"scaleTuning 100", map[SFGenerator]int16{Gen_ScaleTuning: 100}, &SampleHeader{OriginalPitch: 60}, 84, 2400},
+		{"PitchCorrection", nil, &SampleHeader{OriginalPitch: 60, PitchCorrection: -12}, 60, -12},
+		{"everything", map[SFGenerator]int16{Gen_OverridingRootKey: 57, Gen_ScaleTuning: 50, Gen_CoarseTune: 1, Gen_FineTune: 7}, &SampleHeader{OriginalPitch: 60, PitchCorrection: 5}, 69, 712},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Region{Generators: GeneratorDefaults, Sample: tt.sample}
+			for op, v := range tt.gens {
+				r.Generators[op] = v
+			}
+
+			if got := PitchCents(r, tt.key); got != tt.cents {
+				t.Errorf("PitchCents = %v, want %v", got, tt.cents)
+			}
+			ratio := math.Pow(2, tt.cents/1200)
+			if got := PitchRatio(r, tt.key); math.Abs(got-ratio) > 1e-12 {
+				t.Errorf("PitchRatio = %v, want %v", got, ratio)
+			}
+		})
+	}
+}
+
+func TestPhaseIncrement(t *testing.T) {
+	tests := []struct {
+		name       string
+		sample     *SampleHeader
+		key        uint8
+		outputRate float64
+		want       float64
+	}{
+		{"same rate", &SampleHeader{OriginalPitch: 60, SampleRate: 44100}, 60, 44100, 1},
+		{"half rate sample", &SampleHeader{OriginalPitch: 60, SampleRate: 22050}, 60, 44100, 0.5},
+		{"octave up at half rate", &SampleHeader{OriginalPitch: 60, SampleRate: 22050}, 72, 44100, 1},
+		{"higher output rate", &SampleHeader{OriginalPitch: 60, SampleRate: 48000}, 60, 96000, 0.5},
+		{"zero sample rate", &SampleHeader{OriginalPitch: 60}, 72, 44100, 2},
+		{"no output rate", &SampleHeader{OriginalPitch: 60, SampleRate: 22050}, 72, 0, 2},
+		{"no sample", nil, 48, 44100, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Region{Generators: GeneratorDefaults, Sample: tt.sample}
+			if got := PhaseIncrement(r, tt.key, tt.outputRate); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("PhaseIncrement = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
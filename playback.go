@@ -0,0 +1,126 @@
+package sf
+
+import "fmt"
+
+// SampleMode is the value of the sampleModes generator, describing how a
+// sample loops.
+type SampleMode uint16
+
+const (
+	// SampleMode_NoLoop plays the sample from start to end once.
+	SampleMode_NoLoop SampleMode = 0
+	// SampleMode_Continuous loops for as long as the voice sounds, including
+	// during the release phase.
+	SampleMode_Continuous SampleMode = 1
+	// SampleMode_Unused is reserved by the spec and behaves like NoLoop.
+	SampleMode_Unused SampleMode = 2
+	// SampleMode_LoopUntilRelease loops while the key is held, then plays on
+	// from the loop through to the end of the sample.
+	SampleMode_LoopUntilRelease SampleMode = 3
+)
+
+func (m SampleMode) String() string {
+	switch m {
+	case SampleMode_NoLoop:
+		return "NoLoop"
+	case SampleMode_Continuous:
+		return "Continuous"
+	case SampleMode_Unused:
+		return "Unused"
+	case SampleMode_LoopUntilRelease:
+		return "LoopUntilRelease"
+	}
+	return fmt.Sprintf("Unknown(%d)", uint16(m))
+}
+
+// loops reports whether the mode loops at all.
+func (m SampleMode) loops() bool {
+	return m == SampleMode_Continuous || m == SampleMode_LoopUntilRelease
+}
+
+// SampleMode returns the region's loop behavior. Only the low two bits of the
+// generator are meaningful.
+func (r *Region) SampleMode() SampleMode {
+	return SampleMode(uint16(r.Gen(Gen_SampleModes)) & 3)
+}
+
+// Playback walks the sample data points a voice reads, following the region's
+// loop behavior. Positions are indices into the sample data field.
+type Playback struct {
+	start, end         float64
+	loopStart, loopEnd float64
+	mode               SampleMode
+
+	pos      float64
+	released bool
+	done     bool
+}
+
+// NewPlayback starts playback of a region at its first data point, with the
+// address offset generators applied. An empty loop disables looping.
+func NewPlayback(r *Region) *Playback {
+	a := r.Addresses()
+	p := &Playback{
+		mode:      r.SampleMode(),
+		start:     float64(a.Start),
+		end:       float64(a.End),
+		loopStart: float64(a.Startloop),
+		loopEnd:   float64(a.Endloop),
+	}
+
+	if p.loopEnd <= p.loopStart || p.loopStart < p.start || p.loopEnd > p.end {
+		p.mode = SampleMode_NoLoop
+	}
+
+	p.pos = p.start
+	p.done = p.start >= p.end
+	return p
+}
+
+// Mode returns the loop behavior in effect, which is NoLoop when the region's
+// loop points were unusable.
+func (p *Playback) Mode() SampleMode {
+	return p.mode
+}
+
+// looping reports whether the loop is currently active.
+func (p *Playback) looping() bool {
+	switch p.mode {
+	case SampleMode_Continuous:
+		return true
+	case SampleMode_LoopUntilRelease:
+		return !p.released
+	}
+	return false
+}
+
+// Next returns the current position and advances it by step data points. ok is
+// false once playback has run off the end of the sample.
+func (p *Playback) Next(step float64) (pos float64, ok bool) {
+	if p.done {
+		return 0, false
+	}
+
+	pos = p.pos
+	p.pos += step
+	if p.looping() {
+		for p.pos >= p.loopEnd {
+			p.pos -= p.loopEnd - p.loopStart
+		}
+	} else if p.pos >= p.end {
+		p.done = true
+	}
+
+	return pos, true
+}
+
+// Release signals note-off. In LoopUntilRelease mode playback leaves the loop
+// and continues to the end of the sample, other modes are unaffected.
+func (p *Playback) Release() {
+	p.released = true
+}
+
+// Done reports whether playback has reached the end of the sample.
+func (p *Playback) Done() bool {
+	return p.done
+}
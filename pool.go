@@ -0,0 +1,194 @@
+package sf
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// MemoryUsage is the approximate number of bytes a loaded SoundFont holds.
+type MemoryUsage struct {
+	// Samples is the sample data, both the 16-bit and 24-bit parts.
+	Samples int64
+	// Hydra is the preset, instrument and sample header tables.
+	Hydra int64
+	// Info is the INFO chunk's strings.
+	Info int64
+}
+
+// Total returns the sum of all parts.
+func (u MemoryUsage) Total() int64 {
+	return u.Samples + u.Hydra + u.Info
+}
+
+// sliceBytes sums the backing array sizes of the slice fields of the struct
+// pointed to by v.
+func sliceBytes(v interface{}) int64 {
+	rv := reflect.ValueOf(v).Elem()
+	var n int64
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Field(i)
+		if f.Kind() == reflect.Slice {
+			n += int64(f.Cap()) * int64(f.Type().Elem().Size())
+		}
+	}
+	return n
+}
+
+// MemoryUsage reports how much memory the SoundFont's data takes up. It does
+// not include the small fixed size structs holding it.
+func (sf *SoundFont) MemoryUsage() MemoryUsage {
+	var u MemoryUsage
+	if sf.Samples != nil {
+		u.Samples = sliceBytes(sf.Samples)
+	}
+	if sf.Hydra != nil {
+		u.Hydra = sliceBytes(sf.Hydra)
+	}
+	if sf.Info != nil {
+		rv := reflect.ValueOf(sf.Info).Elem()
+		for i := 0; i < rv.NumField(); i++ {
+			if f := rv.Field(i); f.Kind() == reflect.String {
+				u.Info += int64(f.Len())
+			}
+		}
+	}
+	return u
+}
+
+// Metric names reported by Pool.
+const (
+	// MetricPoolHits counts Pool.Get calls served from memory.
+	MetricPoolHits = "sf_pool_hits_total"
+	// MetricPoolMisses counts Pool.Get calls that loaded a bank.
+	MetricPoolMisses = "sf_pool_misses_total"
+	// MetricPoolEvictions counts banks evicted to stay under budget.
+	MetricPoolEvictions = "sf_pool_evictions_total"
+	// MetricPoolBytes is the memory used by the banks in a pool.
+	MetricPoolBytes = "sf_pool_bytes"
+)
+
+type poolEntry struct {
+	name string
+	sf   *SoundFont
+	size int64
+}
+
+// poolCall is a load in progress, which other Gets of the same name wait on.
+type poolCall struct {
+	done chan struct{}
+	sf   *SoundFont
+	err  error
+}
+
+// Pool keeps loaded banks in memory up to a byte budget, evicting the least
+// recently used banks when a new one does not fit. It is safe for concurrent
+// use. Banks load without holding up Gets of other banks, and concurrent Gets
+// of a bank being loaded wait for that load rather than starting another.
+type Pool struct {
+	budget int64
+	load   func(name string) (*SoundFont, error)
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List // front is the most recently used
+	used     int64
+	inflight map[string]*poolCall
+}
+
+// NewPool returns a pool that calls load for banks it doesn't hold and keeps
+// at most budget bytes, as measured by MemoryUsage, in memory.
+func NewPool(budget int64, load func(name string) (*SoundFont, error)) *Pool {
+	return &Pool{
+		budget:   budget,
+		load:     load,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*poolCall),
+	}
+}
+
+// Get returns the named bank, loading it if it isn't in the pool. A bank
+// larger than the whole budget is an error.
+func (p *Pool) Get(name string) (*SoundFont, error) {
+	m := currentMetrics()
+
+	p.mu.Lock()
+	if el, ok := p.entries[name]; ok {
+		m.Count(MetricPoolHits, 1)
+		p.lru.MoveToFront(el)
+		p.mu.Unlock()
+		return el.Value.(*poolEntry).sf, nil
+	}
+	if c, ok := p.inflight[name]; ok {
+		// served by the load already under way, so not a miss
+		m.Count(MetricPoolHits, 1)
+		p.mu.Unlock()
+		<-c.done
+		return c.sf, c.err
+	}
+	m.Count(MetricPoolMisses, 1)
+	c := &poolCall{done: make(chan struct{})}
+	p.inflight[name] = c
+	p.mu.Unlock()
+
+	loaded := false
+	defer func() {
+		if !loaded {
+			// load panicked: waiters get an error and the next Get loads again
+			c.sf, c.err = nil, fmt.Errorf("loading bank %q panicked", name)
+			p.mu.Lock()
+			delete(p.inflight, name)
+			p.mu.Unlock()
+		}
+		close(c.done)
+	}()
+	c.sf, c.err = p.load(name)
+	loaded = true
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inflight, name)
+	if c.err != nil {
+		c.sf = nil
+		return nil, c.err
+	}
+	size := c.sf.MemoryUsage().Total()
+	if size > p.budget {
+		c.sf, c.err = nil, fmt.Errorf("bank %q needs %d bytes, more than the pool budget of %d", name, size, p.budget)
+		return nil, c.err
+	}
+
+	for p.used+size > p.budget {
+		p.remove(p.lru.Back())
+		m.Count(MetricPoolEvictions, 1)
+	}
+	p.entries[name] = p.lru.PushFront(&poolEntry{name, c.sf, size})
+	p.used += size
+	m.Gauge(MetricPoolBytes, float64(p.used))
+	return c.sf, nil
+}
+
+// Evict drops the named bank from the pool, if present.
+func (p *Pool) Evict(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if el, ok := p.entries[name]; ok {
+		p.remove(el)
+		currentMetrics().Gauge(MetricPoolBytes, float64(p.used))
+	}
+}
+
+func (p *Pool) remove(el *list.Element) {
+	e := p.lru.Remove(el).(*poolEntry)
+	delete(p.entries, e.name)
+	p.used -= e.size
+}
+
+// Used returns the bytes taken by the banks in the pool.
+func (p *Pool) Used() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.used
+}
@@ -0,0 +1,109 @@
+package sf
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolLoadsOnce(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	p := NewPool(1<<30, func(name string) (*SoundFont, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return GenerateSineBank(1), nil
+	})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	banks := make([]*SoundFont, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sf, err := p.Get("bank")
+			if err != nil {
+				t.Error(err)
+			}
+			banks[i] = sf
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("bank loaded %d times, want once", n)
+	}
+	for i, sf := range banks {
+		if sf != banks[0] {
+			t.Errorf("caller %d got a different bank", i)
+		}
+	}
+}
+
+func TestPoolLoadDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	p := NewPool(1<<30, func(name string) (*SoundFont, error) {
+		if name == "slow" {
+			<-release
+		}
+		return GenerateSineBank(1), nil
+	})
+	defer close(release)
+
+	go p.Get("slow")
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		p.Get("fast")
+		p.Used()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a slow load held up the pool")
+	}
+}
+
+func TestPoolLoadPanics(t *testing.T) {
+	var loads int32
+	started, release := make(chan struct{}), make(chan struct{})
+	p := NewPool(1<<30, func(name string) (*SoundFont, error) {
+		if atomic.AddInt32(&loads, 1) == 1 {
+			close(started)
+			<-release
+			panic("corrupt bank")
+		}
+		return GenerateSineBank(1), nil
+	})
+
+	go func() {
+		defer func() { recover() }()
+		p.Get("bank")
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		sf, err := p.Get("bank")
+		if sf != nil {
+			err = nil
+		}
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-waiter; err == nil {
+		t.Error("a Get waiting on a panicking load got no error")
+	}
+
+	sf, err := p.Get("bank")
+	if err != nil || sf == nil {
+		t.Errorf("Get after a panicking load returned %v, %v, want a fresh load", sf, err)
+	}
+}
@@ -0,0 +1,79 @@
+package sf
+
+import "fmt"
+
+// rangeBytes splits a keyRange or velRange amount into its lo and hi bytes.
+func rangeBytes(amount int16) (lo, hi uint8) {
+	return uint8(amount), uint8(uint16(amount) >> 8)
+}
+
+// makeRange packs lo and hi into a range generator amount.
+func makeRange(lo, hi uint8) int16 {
+	return int16(uint16(hi)<<8 | uint16(lo))
+}
+
+// checkRange describes what is wrong with a range amount, or returns "" when
+// it is sane.
+func checkRange(amount int16) string {
+	lo, hi := rangeBytes(amount)
+	switch {
+	case lo > 127 || hi > 127:
+		return fmt.Sprintf("range %d-%d exceeds 0-127", lo, hi)
+	case lo > hi:
+		return fmt.Sprintf("range %d-%d is inverted", lo, hi)
+	}
+	return ""
+}
+
+// validateRanges reports inverted or out of range keyRange and velRange
+// generators in a zone.
+func validateRanges(where string, zone Zone) []Problem {
+	var problems []Problem
+	for _, g := range zone.Generators {
+		if g.GenOper != Gen_KeyRange && g.GenOper != Gen_VelRange {
+			continue
+		}
+		if msg := checkRange(g.GenAmount.AsInt16()); msg != "" {
+			name := "keyRange"
+			if g.GenOper == Gen_VelRange {
+				name = "velRange"
+			}
+			problems = append(problems, Problem{"bad-range", where, name + " " + msg})
+		}
+	}
+	return problems
+}
+
+// repairRange swaps inverted bounds and clamps bounds above 127.
+func repairRange(amount int16) int16 {
+	lo, hi := rangeBytes(amount)
+	if lo > 127 {
+		lo = 127
+	}
+	if hi > 127 {
+		hi = 127
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return makeRange(lo, hi)
+}
+
+// RepairRanges fixes every keyRange and velRange generator in place: bounds
+// above 127 are clamped and inverted ranges swapped. It returns how many
+// generators were changed.
+func (h *SoundFontHydra) RepairRanges() int {
+	changed := 0
+	for _, gens := range [][]Generator{h.PresetGenerators, h.InstrumentGenerators} {
+		for i := range gens {
+			if gens[i].GenOper != Gen_KeyRange && gens[i].GenOper != Gen_VelRange {
+				continue
+			}
+			if fixed := repairRange(gens[i].GenAmount.AsInt16()); fixed != gens[i].GenAmount.AsInt16() {
+				gens[i].GenAmount = GenAmount(fixed)
+				changed++
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,255 @@
+package sf
+
+import "fmt"
+
+// GeneratorDefaults holds the default value of every generator, used when
+// neither the instrument zone nor its global zone sets it.
+var GeneratorDefaults = [Gen_EndOper]int16{
+	Gen_InitialFilterFc:   13500,
+	Gen_DelayModLFO:       -12000,
+	Gen_DelayVibLFO:       -12000,
+	Gen_DelayModEnv:       -12000,
+	Gen_AttackModEnv:      -12000,
+	Gen_HoldModEnv:        -12000,
+	Gen_DecayModEnv:       -12000,
+	Gen_ReleaseModEnv:     -12000,
+	Gen_DelayVolEnv:       -12000,
+	Gen_AttackVolEnv:      -12000,
+	Gen_HoldVolEnv:        -12000,
+	Gen_DecayVolEnv:       -12000,
+	Gen_ReleaseVolEnv:     -12000,
+	Gen_KeyRange:          127 << 8,
+	Gen_VelRange:          127 << 8,
+	Gen_Keynum:            -1,
+	Gen_Velocity:          -1,
+	Gen_ScaleTuning:       100,
+	Gen_OverridingRootKey: -1,
+}
+
+// Region is a fully resolved layer of a note: one instrument zone reached
+// through one preset zone, with the generator values the voice plays with.
+type Region struct {
+	// Preset and Instrument are the zones the region was resolved from.
+	Preset, Instrument *Zone
+
+	// SampleIndex is the index of Sample in SoundFontHydra.Samples.
+	SampleIndex int
+	Sample      *SampleHeader
+
+	// Generators holds the effective value of every generator: the instrument
+	// level value (or its default) plus the preset level offset.
+	Generators [Gen_EndOper]int16
+
+	// Modulators are the effective modulators of the region.
+	Modulators []Modulator
+
+	// Key and Velocity are what the voice plays with, after the keynum and
+	// velocity generators have overridden the played values. Everything keyed
+	// off the note (key scaled envelopes, modulator sources, pitch) should use
+	// these rather than the played key and velocity.
+	Key, Velocity uint8
+}
+
+// Gen returns the effective value of the generator op.
+func (r *Region) Gen(op SFGenerator) int16 {
+	if op >= Gen_EndOper {
+		return 0
+	}
+	return r.Generators[op]
+}
+
+// zoneValues returns the generator values of a zone, layered over its global
+// zone. set reports which generators were present in either.
+func zoneValues(z *Zone) (values [Gen_EndOper]int16, set [Gen_EndOper]bool) {
+	apply := func(gens []Generator) {
+		for _, g := range gens {
+			if g.GenOper >= Gen_EndOper {
+				continue
+			}
+			values[g.GenOper] = g.GenAmount.AsInt16()
+			set[g.GenOper] = true
+		}
+	}
+	if z.Global != nil {
+		apply(z.Global.Generators)
+	}
+	apply(z.Generators)
+	return values, set
+}
+
+// inRange reports whether v falls inside the lo/hi byte pair of a range
+// generator amount.
+func inRange(amount int16, v uint8) bool {
+	lo, hi := uint8(amount), uint8(uint16(amount)>>8)
+	return lo <= v && v <= hi
+}
+
+// Regions resolves every region the preset at index preset plays for the given
+// key and velocity.
+func (h *SoundFontHydra) Regions(preset int, key, vel uint8) ([]Region, error) {
+	return h.regions(preset, key, vel, false)
+}
+
+// PresetRegions resolves every region of the preset at index preset, whatever
+// the key and velocity. The key and velocity range generators of each region
+// are narrowed to the part its preset zone also covers, and regions where the
+// two do not overlap are left out. Key and Velocity are 0 unless the keynum
+// and velocity generators force them.
+func (h *SoundFontHydra) PresetRegions(preset int) ([]Region, error) {
+	return h.regions(preset, 0, 0, true)
+}
+
+// intersectRange returns the overlap of two range generator amounts.
+func intersectRange(a, b int16) (int16, bool) {
+	aLo, aHi := rangeBytes(a)
+	bLo, bHi := rangeBytes(b)
+	if bLo > aLo {
+		aLo = bLo
+	}
+	if bHi < aHi {
+		aHi = bHi
+	}
+	return makeRange(aLo, aHi), aLo <= aHi
+}
+
+// regions resolves the regions of a preset, those played by key and vel or,
+// when all is set, every one.
+func (h *SoundFontHydra) regions(preset int, key, vel uint8, all bool) ([]Region, error) {
+	presetZones, err := h.PresetZones(preset)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []Region
+	for pi := range presetZones {
+		pz := &presetZones[pi]
+		instrument, ok := pz.terminal(Gen_Instrument)
+		if !ok {
+			// the global zone, or a zone without an instrument
+			continue
+		}
+
+		pValues, pSet := zoneValues(pz)
+		if !all && pSet[Gen_KeyRange] && !inRange(pValues[Gen_KeyRange], key) {
+			continue
+		}
+		if !all && pSet[Gen_VelRange] && !inRange(pValues[Gen_VelRange], vel) {
+			continue
+		}
+
+		instZones, err := h.InstrumentZones(int(uint16(instrument)))
+		if err != nil {
+			return nil, fmt.Errorf("preset %d: %w", preset, err)
+		}
+
+		for ii := range instZones {
+			iz := &instZones[ii]
+			sample, ok := iz.terminal(Gen_SampleID)
+			if !ok {
+				continue
+			}
+
+			iValues, iSet := zoneValues(iz)
+			r := Region{
+				Preset:      pz,
+				Instrument:  iz,
+				SampleIndex: int(uint16(sample)),
+				Modulators:  EffectiveModulators(iz, pz),
+				Key:         key,
+				Velocity:    vel,
+			}
+			if r.SampleIndex >= len(h.Samples) {
+				return nil, fmt.Errorf("preset %d: sample index %d out of range", preset, r.SampleIndex)
+			}
+			r.Sample = &h.Samples[r.SampleIndex]
+
+			for op := SFGenerator(0); op < Gen_EndOper; op++ {
+				r.Generators[op] = GeneratorDefaults[op]
+				if iSet[op] && InstrumentLegal(op) {
+					r.Generators[op] = iValues[op]
+				}
+			}
+			if all {
+				overlap := true
+				for _, op := range []SFGenerator{Gen_KeyRange, Gen_VelRange} {
+					if pSet[op] {
+						var ok bool
+						r.Generators[op], ok = intersectRange(r.Generators[op], pValues[op])
+						overlap = overlap && ok
+					}
+				}
+				if !overlap {
+					continue
+				}
+			} else if !inRange(r.Generators[Gen_KeyRange], key) || !inRange(r.Generators[Gen_VelRange], vel) {
+				continue
+			}
+
+			// preset level generators are offsets added to the instrument
+			// level, generators illegal at the preset level are ignored
+			for op := SFGenerator(0); op < Gen_EndOper; op++ {
+				switch op {
+				case Gen_KeyRange, Gen_VelRange, Gen_Instrument:
+					continue
+				}
+				if pSet[op] && PresetLegal(op) {
+					r.Generators[op] = clampInt16(int(r.Generators[op]) + int(pValues[op]))
+				}
+			}
+
+			// keynum and velocity force the values the voice is played with
+			if k := r.Generators[Gen_Keynum]; k >= 0 && k <= 127 {
+				r.Key = uint8(k)
+			}
+			if v := r.Generators[Gen_Velocity]; v >= 0 && v <= 127 {
+				r.Velocity = uint8(v)
+			}
+
+			regions = append(regions, r)
+		}
+	}
+
+	return regions, nil
+}
+
+// Addresses is the span of sample data a region plays, as indices into the
+// sample data field.
+type Addresses struct {
+	Start, End         uint32
+	Startloop, Endloop uint32
+}
+
+// Addresses applies the eight address offset generators to the region's sample
+// header. Each point moves by its fine offset plus 32768 times its coarse
+// offset. Start and End are kept inside the sample, and the loop inside
+// Start..End.
+func (r *Region) Addresses() Addresses {
+	if r.Sample == nil {
+		return Addresses{}
+	}
+
+	offset := func(base uint32, fine, coarse SFGenerator) int64 {
+		return int64(base) + int64(r.Gen(fine)) + 32768*int64(r.Gen(coarse))
+	}
+	clamp := func(v, lo, hi int64) uint32 {
+		if v < lo {
+			v = lo
+		}
+		if v > hi {
+			v = hi
+		}
+		return uint32(v)
+	}
+
+	lo, hi := int64(r.Sample.Start), int64(r.Sample.End)
+	if hi < lo {
+		hi = lo
+	}
+
+	var a Addresses
+	a.Start = clamp(offset(r.Sample.Start, Gen_StartAddrsOffset, Gen_StartAddrsCoarseOffset), lo, hi)
+	a.End = clamp(offset(r.Sample.End, Gen_EndAddrsOffset, Gen_EndAddrsCoarseOffset), int64(a.Start), hi)
+	a.Startloop = clamp(offset(r.Sample.Startloop, Gen_StartloopAddrsOffset, Gen_StartloopAddrsCoarseOffset), int64(a.Start), int64(a.End))
+	a.Endloop = clamp(offset(r.Sample.Endloop, Gen_EndloopAddrsOffset, Gen_EndloopAddrsCoarseOffset), int64(a.Start), int64(a.End))
+	return a
+}
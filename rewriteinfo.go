@@ -0,0 +1,113 @@
+package sf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RewriteInfo replaces the INFO list of the SoundFont file at path with info,
+// in place, without touching the sample data or the hydra after it. It is
+// the fast path for metadata edits to large banks: only the INFO list is
+// written. The new list must fit in the space of the old one; the space left
+// over goes to extra terminating zeros of the isng field or, when that would
+// make it too long, to a JUNK subchunk, which readers skip. A list that does
+// not fit is an error and the file is left as it was, SaveSoundFont rewrites
+// the whole file instead. A zero SfVersion keeps the file's version.
+func RewriteInfo(path string, info *SoundFontInfo) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := rewriteInfo(f, info); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// infoListOffset is where the body of the INFO list starts in a file: after
+// the RIFF header, the sfbk form type and the LIST header.
+const infoListOffset = 12 + 8
+
+func rewriteInfo(f io.ReadWriteSeeker, info *SoundFontInfo) error {
+	var header [infoListOffset + 4]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("sfbk")) ||
+		!bytes.Equal(header[12:16], []byte("LIST")) || !bytes.Equal(header[20:24], []byte("INFO")) {
+		return fmt.Errorf("not a SoundFont starting with its INFO list")
+	}
+	size := int(binary.LittleEndian.Uint32(header[16:20]))
+	if size%2 == 1 {
+		return fmt.Errorf("INFO list size %d is odd", size)
+	}
+
+	old := make([]byte, size)
+	copy(old, header[20:])
+	if _, err := io.ReadFull(f, old[4:]); err != nil {
+		return err
+	}
+	oldInfo, err := ReadSoundFontInfo(bytes.NewReader(old))
+	if err != nil {
+		return err
+	}
+
+	next := *info
+	if next.SfVersion.Major == 0 && next.SfVersion.Minor == 0 {
+		next.SfVersion = oldInfo.SfVersion
+	}
+	body, err := encodeInfo(&next, nil)
+	if err != nil {
+		return err
+	}
+	if len(body) > size {
+		return fmt.Errorf("new INFO list of %d bytes does not fit in the %d bytes of the old one", len(body), size)
+	}
+	if body, err = fillInfo(body, size-len(body)); err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(infoListOffset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	return err
+}
+
+// fillInfo grows an encoded INFO list by gap bytes, an even number, without
+// changing what it says.
+func fillInfo(body []byte, gap int) ([]byte, error) {
+	if gap == 0 {
+		return body, nil
+	}
+
+	// isng directly follows ifil, which always holds 4 bytes
+	const isng = 4 + 8 + 4
+	n := int(binary.LittleEndian.Uint32(body[isng+4:]))
+	if n+gap <= 256 {
+		out := make([]byte, 0, len(body)+gap)
+		out = append(out, body[:isng+8+n]...)
+		out = append(out, make([]byte, gap)...)
+		out = append(out, body[isng+8+n:]...)
+		binary.LittleEndian.PutUint32(out[isng+4:], uint32(n+gap))
+		return out, nil
+	}
+
+	if gap < 8 {
+		return nil, fmt.Errorf("cannot fill %d bytes of the old INFO list", gap)
+	}
+	var buf bytes.Buffer
+	buf.Write(body)
+	if err := writeChunk(&buf, [4]byte{'J', 'U', 'N', 'K'}, make([]byte, gap-8)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package sf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriteInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(info *SoundFontInfo)
+		fits bool
+	}{
+		{"same size", func(info *SoundFontInfo) { info.Name = strings.Repeat("n", len(strings.TrimRight(info.Name, "\x00"))) }, true},
+		{"shorter", func(info *SoundFontInfo) { info.Name = "x" }, true},
+		{"field dropped", func(info *SoundFontInfo) { info.Comments = "" }, true},
+		{"longer", func(info *SoundFontInfo) { info.Comments += strings.Repeat("c", 100) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := GenerateSineBank(2)
+			bank.Info.Comments = strings.Repeat("x", 500)
+			path := filepath.Join(t.TempDir(), "bank.sf2")
+			if err := SaveSoundFont(path, bank, false); err != nil {
+				t.Fatal(err)
+			}
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info := *bank.Info
+			tt.edit(&info)
+			err = RewriteInfo(path, &info)
+			after, rerr := os.ReadFile(path)
+			if rerr != nil {
+				t.Fatal(rerr)
+			}
+			if !tt.fits {
+				if err == nil {
+					t.Fatal("rewrote an INFO list larger than the old one")
+				}
+				if !bytes.Equal(before, after) {
+					t.Error("a failed rewrite changed the file")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(after) != len(before) {
+				t.Errorf("file grew from %d to %d bytes", len(before), len(after))
+			}
+
+			read, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []struct{ got, want string }{
+				{read.Info.Name, info.Name},
+				{read.Info.Comments, info.Comments},
+				{read.Info.Engine, bank.Info.Engine},
+			} {
+				if got := strings.TrimRight(f.got, "\x00"); got != strings.TrimRight(f.want, "\x00") {
+					t.Errorf("read back %q, want %q", got, f.want)
+				}
+			}
+			if !reflect.DeepEqual(read.Hydra, bank.Hydra) || !reflect.DeepEqual(read.Samples.SamplesHigher, bank.Samples.SamplesHigher) {
+				t.Error("the hydra or samples changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package sf
+
+import "sort"
+
+// RoundRobinStrategy selects how round-robin variants are laid out. SF2 has no
+// way to cycle through samples on repeated notes, so every strategy trades
+// something away.
+type RoundRobinStrategy int
+
+const (
+	// RoundRobin_FirstOnly keeps only the lowest numbered variant. Playback is
+	// exactly as authored, minus the variation.
+	RoundRobin_FirstOnly RoundRobinStrategy = iota
+	// RoundRobin_VelocitySplit divides each velocity layer between its
+	// variants. Every variant stays reachable, but which one sounds depends on
+	// how hard the key is hit rather than on repetition, and a layer narrower
+	// than its variant count drops the extra variants.
+	RoundRobin_VelocitySplit
+	// RoundRobin_KeyAlternate gives the variants alternating keys within the
+	// mapped key range. Repeated notes on one key still sound the same, but
+	// runs and chords vary. Costs one zone per key.
+	RoundRobin_KeyAlternate
+)
+
+// ZoneMapping places one sample file in an instrument.
+type ZoneMapping struct {
+	// File is the index of the sample in the slice given to MapSampleFiles.
+	File int
+
+	KeyLo, KeyHi uint8
+	VelLo, VelHi uint8
+
+	// RootKey is the key the sample was recorded at.
+	RootKey uint8
+}
+
+// MapSampleFiles lays out the sample files of one instrument as zones. Key
+// ranges are spread halfway to the neighboring root keys, each velocity layer
+// reaches from just above the layer below it up to its own velocity (files
+// without a velocity are the top layer), and round-robin variants are laid out
+// by strategy. Files without a key are skipped.
+func MapSampleFiles(files []SampleFileInfo, strategy RoundRobinStrategy) []ZoneMapping {
+	type variant struct {
+		file, rr int
+	}
+	// layers[key][velocity] holds the variants of one layer
+	layers := make(map[int]map[int][]variant)
+	for i, f := range files {
+		if f.Key < 0 || f.Key > 127 {
+			continue
+		}
+		vel := f.Velocity
+		if vel < 0 || vel > 127 {
+			vel = 127
+		}
+		if layers[f.Key] == nil {
+			layers[f.Key] = make(map[int][]variant)
+		}
+		layers[f.Key][vel] = append(layers[f.Key][vel], variant{i, f.RoundRobin})
+	}
+
+	keys := make([]int, 0, len(layers))
+	for k := range layers {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	var mappings []ZoneMapping
+	for ki, key := range keys {
+		keyLo, keyHi := 0, 127
+		if ki > 0 {
+			keyLo = (keys[ki-1]+key)/2 + 1
+		}
+		if ki+1 < len(keys) {
+			keyHi = (key + keys[ki+1]) / 2
+		}
+
+		vels := make([]int, 0, len(layers[key]))
+		for v := range layers[key] {
+			vels = append(vels, v)
+		}
+		sort.Ints(vels)
+
+		for vi, vel := range vels {
+			velLo, velHi := 0, vel
+			if vi > 0 {
+				velLo = vels[vi-1] + 1
+			}
+			if vi+1 == len(vels) {
+				velHi = 127
+			}
+
+			variants := layers[key][vel]
+			sort.SliceStable(variants, func(i, j int) bool { return variants[i].rr < variants[j].rr })
+
+			add := func(file, klo, khi, vlo, vhi int) {
+				mappings = append(mappings, ZoneMapping{
+					File:    file,
+					KeyLo:   uint8(klo),
+					KeyHi:   uint8(khi),
+					VelLo:   uint8(vlo),
+					VelHi:   uint8(vhi),
+					RootKey: uint8(key),
+				})
+			}
+
+			n := len(variants)
+			switch {
+			case n == 1 || strategy == RoundRobin_FirstOnly:
+				add(variants[0].file, keyLo, keyHi, velLo, velHi)
+			case strategy == RoundRobin_VelocitySplit:
+				width := velHi - velLo + 1
+				if n > width {
+					n = width
+				}
+				for i := 0; i < n; i++ {
+					lo := velLo + width*i/n
+					hi := velLo + width*(i+1)/n - 1
+					add(variants[i].file, keyLo, keyHi, lo, hi)
+				}
+			case strategy == RoundRobin_KeyAlternate:
+				for k := keyLo; k <= keyHi; k++ {
+					i := ((k-key)%n + n) % n
+					add(variants[i].file, k, k, velLo, velHi)
+				}
+			}
+		}
+	}
+
+	return mappings
+}
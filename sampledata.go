@@ -0,0 +1,85 @@
+package sf
+
+// isROM reports whether the sample lives in a wavetable ROM rather than the
+// smpl sub-chunk.
+func (s SampleHeader) isROM() bool {
+	return s.SampleType&0x8000 != 0
+}
+
+// SampleData returns the data points of a sample, from Start up to End, and
+// for 24-bit banks their least significant bytes. ROM samples have no data.
+// The returned slices share memory with sf.Samples.
+func (sf *SoundFont) SampleData(s SampleHeader) (higher []int16, lower []int8) {
+	if sf.Samples == nil || s.isROM() {
+		return nil, nil
+	}
+
+	start, end := int(s.Start), int(s.End)
+	if end > len(sf.Samples.SamplesHigher) {
+		end = len(sf.Samples.SamplesHigher)
+	}
+	if start > end {
+		start = end
+	}
+
+	higher = sf.Samples.SamplesHigher[start:end]
+	if end <= len(sf.Samples.SamplesLower) {
+		lower = sf.Samples.SamplesLower[start:end]
+	}
+	return higher, lower
+}
+
+// sampleGuard is the number of zero valued data points the spec requires
+// after every sample.
+const sampleGuard = 46
+
+// samplePool builds a new sample data field one sample at a time.
+type samplePool struct {
+	higher []int16
+	lower  []int8
+	// wide is set once any sample with 24-bit data is added
+	wide bool
+}
+
+// add appends a sample's data followed by the zero guard points and returns
+// its header rebased onto the pool. ROM samples are returned unchanged.
+func (p *samplePool) add(s SampleHeader, higher []int16, lower []int8) SampleHeader {
+	if s.isROM() {
+		return s
+	}
+
+	if lower != nil && !p.wide {
+		// earlier samples were 16-bit, give them zero low bytes
+		p.lower = make([]int8, len(p.higher))
+		p.wide = true
+	}
+
+	base := uint32(len(p.higher))
+	shift := func(v uint32) uint32 {
+		if v < s.Start {
+			return base
+		}
+		return v - s.Start + base
+	}
+	s.Startloop = shift(s.Startloop)
+	s.Endloop = shift(s.Endloop)
+	s.Start = base
+	s.End = base + uint32(len(higher))
+
+	p.higher = append(p.higher, higher...)
+	p.higher = append(p.higher, make([]int16, sampleGuard)...)
+	if p.wide {
+		if lower == nil {
+			lower = make([]int8, len(higher))
+		}
+		p.lower = append(p.lower, lower...)
+		p.lower = append(p.lower, make([]int8, sampleGuard)...)
+	}
+
+	return s
+}
+
+// samples returns the pooled sample data.
+func (p *samplePool) samples() *SoundFontSamples {
+	return &SoundFontSamples{SamplesHigher: p.higher, SamplesLower: p.lower}
+}
@@ -0,0 +1,70 @@
+package sf
+
+// linkedSamples returns the samples linked to sample i through SampleLink,
+// following a stereo pair or a whole link chain, without i itself.
+func (h *SoundFontHydra) linkedSamples(i int) []int {
+	var linked []int
+	seen := map[int]bool{i: true}
+	for j := i; h.Samples[j].SampleType&^0x8000 != SampleType_Mono; {
+		j = int(h.Samples[j].SampleLink)
+		if j+1 >= len(h.Samples) || seen[j] {
+			break
+		}
+		seen[j] = true
+		linked = append(linked, j)
+	}
+	return linked
+}
+
+// UpdateSamples applies edit to the header of every sample filter accepts,
+// or of every sample when filter is nil, and returns the indices of the
+// headers it changed. A bulk edit such as setting the rate of all 22050 Hz
+// samples or shifting the root keys of a group of samples by name is one
+// call.
+//
+// Start, End, SampleLink and SampleType are restored after the edit: they
+// describe the sample data and the links between samples, which a header
+// edit cannot change on its own. Linked samples stay consistent: when a
+// sample's SampleRate, OriginalPitch or PitchCorrection changes, the samples
+// linked to it that filter did not select take the same values, so the two
+// sides of a stereo pair keep playing in step.
+func (h *SoundFontHydra) UpdateSamples(filter func(SampleHeader) bool, edit func(*SampleHeader)) []int {
+	selected := map[int]bool{}
+	var changed []int
+	for i := 0; i+1 < len(h.Samples); i++ {
+		s := h.Samples[i]
+		if filter != nil && !filter(s) {
+			continue
+		}
+		selected[i] = true
+
+		edit(&s)
+		old := h.Samples[i]
+		s.Start, s.End, s.SampleLink, s.SampleType = old.Start, old.End, old.SampleLink, old.SampleType
+		if s != old {
+			h.Samples[i] = s
+			changed = append(changed, i)
+		}
+	}
+
+	// range reads changed once, so linked samples appended below are not
+	// followed in turn
+	followed := map[int]bool{}
+	for _, i := range changed {
+		s := h.Samples[i]
+		for _, j := range h.linkedSamples(i) {
+			if selected[j] {
+				continue
+			}
+			l := &h.Samples[j]
+			if l.SampleRate != s.SampleRate || l.OriginalPitch != s.OriginalPitch || l.PitchCorrection != s.PitchCorrection {
+				l.SampleRate, l.OriginalPitch, l.PitchCorrection = s.SampleRate, s.OriginalPitch, s.PitchCorrection
+				if !followed[j] {
+					followed[j] = true
+					changed = append(changed, j)
+				}
+			}
+		}
+	}
+	return changed
+}
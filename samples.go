@@ -1,4 +1,4 @@
-package main
+package sf
 
 import "io"
 
@@ -24,7 +24,7 @@ func ReadSoundFontSamples(r io.Reader) (*SoundFontSamples, error) {
 	// sixteen bit, signed, little endian (least significant byte first) words.
 	sound.SamplesHigher = make([]int16, smplHeader.size/2)
 	for i := 0; i < len(sound.SamplesHigher); i++ {
-		sound.SamplesHigher[i] = int16(smplHeader.data[i*2+1])<<8 | int16(smplHeader.data[i*2])<<8
+		sound.SamplesHigher[i] = int16(smplHeader.data[i*2+1])<<8 | int16(smplHeader.data[i*2])
 	}
 
 	// optionally read the "sm24" sub-chunk
@@ -0,0 +1,102 @@
+package sf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SaveSoundFont writes sf to the file at path without ever leaving it half
+// written: the bank goes to a temporary file in the same directory, which is
+// synced and then renamed over path, so a crash leaves either the old file or
+// the new one. An existing file's permissions carry over to the new one. When
+// backup is set the old file, if there is one, is kept as path + ".bak",
+// replacing any earlier backup. Errors name the file.
+func SaveSoundFont(path string, sf *SoundFont, backup bool) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	old, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	// a no-op once the rename has happened
+	defer os.Remove(tmp.Name())
+
+	if err := writeSynced(tmp, sf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if old != nil {
+		if err := os.Chmod(tmp.Name(), old.Mode().Perm()); err != nil {
+			return err
+		}
+		if backup {
+			if err := copyFile(path, path+".bak"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// make the rename itself durable, where directories can be synced
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// writeSynced writes sf to f and flushes it to disk.
+func writeSynced(f *os.File, sf *SoundFont) error {
+	w := bufio.NewWriter(f)
+	if err := WriteSoundFont(w, sf); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// copyFile copies src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, st.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,67 @@
+package sf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveSoundFont(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bank.sf2")
+
+	if err := SaveSoundFont(path, GenerateSineBank(1), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("a backup was made of a file that did not exist: %v", err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveSoundFont(path, GenerateSineBank(2), true); err != nil {
+		t.Fatal(err)
+	}
+	bak, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bak, first) {
+		t.Error("the backup does not hold the old file")
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("saved file has mode %v, want the old file's 0600", st.Mode().Perm())
+	}
+	sf, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sf.Hydra.Headers) - 1; n != 2 {
+		t.Errorf("saved file has %d presets, want 2", n)
+	}
+
+	// a failed write leaves the old file and no temporary files behind
+	if err := SaveSoundFont(path, &SoundFont{}, false); err == nil {
+		t.Fatal("saved a SoundFont with no INFO")
+	}
+	if _, err := LoadFile(path); err != nil {
+		t.Errorf("the old file is damaged: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d files, want the bank and its backup", len(entries))
+	}
+}
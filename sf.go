@@ -0,0 +1,230 @@
+// Package sf reads SoundFont 2 banks and inspects and edits their presets,
+// instruments and samples. Start with ReadSoundFont or LoadFile.
+package sf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+type SoundFont struct {
+	// Info holds the sound font information present in the INFO chunk.
+	Info *SoundFontInfo
+
+	// Sound holds the sound data present in the SDTA chunk.
+	// Can either be 16-bit or 24-bit samples.
+	Samples *SoundFontSamples
+
+	// The Preset, Instrument, and Sample Header data
+	Hydra *SoundFontHydra
+}
+
+// Expect reads len(b) bytes from r and checks that they match b.
+func Expect(r io.Reader, b []byte) (bool, error) {
+	buf := make([]byte, len(b))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return false, err
+	}
+	return bytes.Equal(buf, b), nil
+}
+
+// ReadOptions controls how forgiving ReadSoundFontWith is of malformed files.
+type ReadOptions struct {
+	// SizeTolerance accepts RIFF and LIST chunks whose stored size is one
+	// byte off from their contents, a mistake some tools make around the
+	// RIFF pad byte.
+	SizeTolerance bool
+
+	// Warn, if set, is told about every mismatch that was tolerated and every
+	// unknown chunk or trailing data that was skipped.
+	Warn func(msg string)
+}
+
+func (o ReadOptions) warn(format string, args ...interface{}) {
+	if o.Warn != nil {
+		o.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// ReadSoundFont reads a SoundFont from r, reporting the load to the metrics
+// set with SetMetrics and the tracer set with SetTracer.
+func ReadSoundFont(r io.Reader) (*SoundFont, error) {
+	return ReadSoundFontWith(r, ReadOptions{})
+}
+
+// ReadSoundFontWith is ReadSoundFont with options.
+func ReadSoundFontWith(r io.Reader, opts ReadOptions) (*SoundFont, error) {
+	start := time.Now()
+	span := startSpan(SpanLoad)
+	sf, err := readSoundFont(r, opts)
+	span.End(err)
+
+	m := currentMetrics()
+	m.Count(MetricLoads, 1)
+	if err != nil {
+		m.Count(MetricParseFailures, 1)
+		return nil, err
+	}
+	m.Observe(MetricLoadSeconds, time.Since(start).Seconds())
+	return sf, nil
+}
+
+// listStream reads the LIST chunks of a RIFF body one after the other from a
+// plain io.Reader. It never seeks and buffers at most one list plus a few
+// bytes of look-ahead, which SizeTolerance uses to find where the next list
+// really starts.
+type listStream struct {
+	r    *bufio.Reader
+	opts ReadOptions
+
+	// carry holds the start of the next header when the previous list's size
+	// took it in.
+	carry []byte
+}
+
+// next reads the data of the next LIST chunk. last is set for the final list,
+// which has no next header to look for.
+func (s *listStream) next(last bool) ([]byte, error) {
+	var header [8]byte
+	n := copy(header[:], s.carry)
+	s.carry = nil
+	if _, err := io.ReadFull(s.r, header[n:]); err != nil {
+		return nil, err
+	}
+	if id := [4]byte{header[0], header[1], header[2], header[3]}; id != [4]byte{'L', 'I', 'S', 'T'} {
+		return nil, fmt.Errorf("expected chunk id %v, got %v", [4]byte{'L', 'I', 'S', 'T'}, id)
+	}
+
+	size := binary.LittleEndian.Uint32(header[4:])
+	data, err := readChunkData(s.r, size)
+	if err == io.ErrUnexpectedEOF && last && s.opts.SizeTolerance && len(data)+1 == int(size) {
+		s.opts.warn("LIST size %d is one more than its contents", size)
+		return data, nil
+	}
+	if err != nil || last || !s.opts.SizeTolerance {
+		return data, err
+	}
+
+	peek, _ := s.r.Peek(5)
+	switch {
+	case bytes.HasPrefix(peek, []byte("LIST")):
+	case len(peek) == 5 && bytes.Equal(peek[1:], []byte("LIST")):
+		b, _ := s.r.ReadByte()
+		// SoundFont lists hold whole, even, subchunks: an odd size left
+		// out the last byte, after an even one the byte is stray
+		if len(data)%2 == 1 {
+			s.opts.warn("LIST size %d is one less than its contents", size)
+			data = append(data, b)
+		} else {
+			s.opts.warn("LIST of size %d is followed by a stray byte", size)
+		}
+	case len(data) > 0 && data[len(data)-1] == 'L' && bytes.HasPrefix(peek, []byte("IST")):
+		s.opts.warn("LIST size %d is one more than its contents", size)
+		data = data[:len(data)-1]
+		s.carry = []byte{'L'}
+	}
+	return data, nil
+}
+
+func readSoundFont(r io.Reader, opts ReadOptions) (*SoundFont, error) {
+	// Read the RIFF header, its size bounds everything read after it.
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if id := [4]byte{header[0], header[1], header[2], header[3]}; id != [4]byte{'R', 'I', 'F', 'F'} {
+		return nil, fmt.Errorf("expected chunk id %v, got %v", [4]byte{'R', 'I', 'F', 'F'}, id)
+	}
+	size := int64(binary.LittleEndian.Uint32(header[4:]))
+	if opts.SizeTolerance {
+		// the last list may run a byte past a RIFF size that is one short
+		size++
+	}
+	r = io.LimitReader(r, size)
+
+	// read "sfbk" from the RIFF header
+	ok, err := Expect(r, []byte{'s', 'f', 'b', 'k'})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected sfbk")
+	}
+
+	// read the first "LIST" header
+	lists := &listStream{r: bufio.NewReader(r), opts: opts}
+	data, err := lists.next(false)
+	if err != nil {
+		return nil, err
+	}
+	listReader := bytes.NewReader(data)
+
+	span := startSpan(SpanInfo)
+	info, err := readSoundFontInfo(listReader, opts)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the next "LIST" header
+	if data, err = lists.next(false); err != nil {
+		return nil, err
+	}
+	listReader = bytes.NewReader(data)
+
+	// read "sdta" from the "LIST" header
+	ok, err = Expect(listReader, []byte{'s', 'd', 't', 'a'})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected sdta")
+	}
+	span = startSpan(SpanSdta)
+	sound, err := ReadSoundFontSamples(listReader)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the last "LIST" header
+	if data, err = lists.next(true); err != nil {
+		return nil, err
+	}
+	listReader = bytes.NewReader(data)
+
+	// read "pdta" from the "LIST" header
+	ok, err = Expect(listReader, []byte{'p', 'd', 't', 'a'})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected pdta")
+	}
+
+	span = startSpan(SpanHydra)
+	hydra, err := readSoundFontHydra(listReader, opts)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	// sink remaining data
+	n, err := io.Copy(io.Discard, listReader)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		opts.warn("skipped %d bytes after the pdta list", n)
+	}
+
+	return &SoundFont{
+		Info:    info,
+		Samples: sound,
+		Hydra:   hydra,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package sf
+
+import (
+	"math"
+	"sort"
+)
+
+// similarityFrame is the FFT length used to fingerprint samples, and
+// similarityBands the number of log spaced bands the spectrum is reduced to.
+const (
+	similarityFrame = 1024
+	similarityBands = 32
+
+	// flatSpectrum is the standard deviation of the bands, in dB, below
+	// which a spectrum counts as flat.
+	flatSpectrum = 3
+)
+
+// spectrumFingerprint returns a sample's average spectrum, in dB per log
+// spaced band, centered and normalized to unit length so that it ignores
+// level. Silent samples return nil, and so do samples with a flat spectrum,
+// such as noise: with no shape to compare, any two of them would match.
+func spectrumFingerprint(data []int16) []float64 {
+	if len(data) == 0 {
+		return nil
+	}
+	nyquist := similarityFrame / 2
+	sum := make([]float64, nyquist+1)
+	frames := Spectrogram(data, similarityFrame, similarityFrame/2, HannWindow)
+	for _, mags := range frames {
+		for i, m := range mags {
+			sum[i] += m
+		}
+	}
+
+	bands := make([]float64, similarityBands)
+	for b := range bands {
+		lo := int(math.Pow(float64(nyquist), float64(b)/similarityBands))
+		hi := int(math.Pow(float64(nyquist), float64(b+1)/similarityBands))
+		if hi <= lo {
+			hi = lo + 1
+		}
+		var e float64
+		for i := lo; i < hi; i++ {
+			e += sum[i] / float64(len(frames))
+		}
+		// floor at -100 dB so silence in one band doesn't dominate
+		bands[b] = math.Max(20*math.Log10(e/float64(hi-lo)+1e-5), -100)
+	}
+
+	// center the curve so the similarity compares its shape, not its level
+	var mean, norm float64
+	for _, v := range bands {
+		mean += v / similarityBands
+	}
+	for i := range bands {
+		bands[i] -= mean
+		norm += bands[i] * bands[i]
+	}
+	if mean <= -100 || norm < similarityBands*flatSpectrum*flatSpectrum {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	for i := range bands {
+		bands[i] /= norm
+	}
+	return bands
+}
+
+// SampleCluster is a group of samples that sound alike.
+type SampleCluster struct {
+	// Samples are indexes into Hydra.Samples.
+	Samples []int
+
+	// Similarity is the weakest link that joined the cluster, the correlation
+	// of two fingerprints, 1 meaning identical spectral shapes.
+	Similarity float64
+}
+
+// SimilarSamples groups samples whose average spectra are at least threshold
+// similar (0.95 is a good starting point), and returns the groups with more
+// than one member, most similar first. Unlike a byte comparison this finds
+// copies that were resampled, trimmed, requantized or gain adjusted. Samples
+// of one recording at different pitches generally don't match. Silent samples
+// and samples with a flat spectrum, such as noise, are left out: their spectra
+// have nothing to tell them apart by.
+func (sf *SoundFont) SimilarSamples(threshold float64) []SampleCluster {
+	var index []int
+	var prints [][]float64
+	for i := 0; i+1 < len(sf.Hydra.Samples); i++ {
+		higher, _ := sf.SampleData(sf.Hydra.Samples[i])
+		if p := spectrumFingerprint(higher); p != nil {
+			index = append(index, i)
+			prints = append(prints, p)
+		}
+	}
+
+	// single linkage clustering with union-find
+	parent := make([]int, len(prints))
+	weakest := make([]float64, len(prints))
+	for i := range parent {
+		parent[i] = i
+		weakest[i] = 1
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range prints {
+		for j := i + 1; j < len(prints); j++ {
+			var sim float64
+			for k := range prints[i] {
+				sim += prints[i][k] * prints[j][k]
+			}
+			if sim < threshold {
+				continue
+			}
+			a, b := find(i), find(j)
+			if a != b {
+				parent[b] = a
+				weakest[a] = math.Min(math.Min(weakest[a], weakest[b]), sim)
+			}
+		}
+	}
+
+	groups := map[int]*SampleCluster{}
+	var clusters []*SampleCluster
+	for i := range prints {
+		root := find(i)
+		c, ok := groups[root]
+		if !ok {
+			c = &SampleCluster{Similarity: weakest[root]}
+			groups[root] = c
+			clusters = append(clusters, c)
+		}
+		c.Samples = append(c.Samples, index[i])
+	}
+
+	var out []SampleCluster
+	for _, c := range clusters {
+		if len(c.Samples) > 1 {
+			out = append(out, *c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	return out
+}
@@ -0,0 +1,24 @@
+package sf
+
+import "testing"
+
+func TestSimilarSamplesNoise(t *testing.T) {
+	if clusters := GenerateNoiseBank(4, 1).SimilarSamples(0.95); len(clusters) != 0 {
+		t.Errorf("unrelated noise samples clustered: %v", clusters)
+	}
+}
+
+func TestSimilarSamplesCopies(t *testing.T) {
+	sine := GenerateSineBank(4)
+	higher, _ := sine.SampleData(sine.Hydra.Samples[3])
+	quieter := make([]int16, len(higher))
+	for i, v := range higher {
+		quieter[i] = v / 2
+	}
+	bank := singleZoneBank(newTestInfo("Copies"), []string{"a", "b", "c"}, [][]int16{higher, quieter, GenerateNoiseBank(1, 1).Samples.SamplesHigher[:len(higher)]})
+
+	clusters := bank.SimilarSamples(0.95)
+	if len(clusters) != 1 || len(clusters[0].Samples) != 2 || clusters[0].Samples[0] != 0 || clusters[0].Samples[1] != 1 {
+		t.Errorf("got clusters %v, want the wave and its quieter copy", clusters)
+	}
+}
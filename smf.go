@@ -0,0 +1,179 @@
+package sf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// smfDivision is the ticks per quarter note of the files TestSequence writes.
+const smfDivision = 480
+
+// smfTrack builds the events of a Standard MIDI File track.
+type smfTrack struct {
+	buf  bytes.Buffer
+	last int // tick of the last event
+}
+
+// event appends an event at tick, which must not be before the last one.
+func (t *smfTrack) event(tick int, data ...byte) {
+	delta := uint32(tick - t.last)
+	t.last = tick
+
+	// variable length quantity, most significant group first
+	var vlq [5]byte
+	i := len(vlq) - 1
+	vlq[i] = byte(delta & 0x7f)
+	for delta >>= 7; delta > 0; delta >>= 7 {
+		i--
+		vlq[i] = byte(delta&0x7f) | 0x80
+	}
+	t.buf.Write(vlq[i:])
+	t.buf.Write(data)
+}
+
+func (t *smfTrack) meta(tick int, kind byte, data []byte) {
+	t.event(tick, append([]byte{0xff, kind, byte(len(data))}, data...)...)
+}
+
+// note plays key from tick for length ticks.
+func (t *smfTrack) note(ch byte, tick, length int, key, vel uint8) {
+	t.event(tick, 0x90|ch, key, vel)
+	t.event(tick+length, 0x80|ch, key, 0)
+}
+
+// chord plays keys together from tick for length ticks.
+func (t *smfTrack) chord(ch byte, tick, length int, vel uint8, keys ...uint8) {
+	for _, k := range keys {
+		t.event(tick, 0x90|ch, k, vel)
+	}
+	for _, k := range keys {
+		t.event(tick+length, 0x80|ch, k, 0)
+	}
+}
+
+// writeTo writes a format 0 file holding the track.
+func (t *smfTrack) writeTo(w io.Writer) error {
+	t.meta(t.last, 0x2f, nil)
+
+	var out bytes.Buffer
+	out.WriteString("MThd")
+	binary.Write(&out, binary.BigEndian, []uint32{6})
+	binary.Write(&out, binary.BigEndian, []uint16{0, 1, smfDivision})
+	out.WriteString("MTrk")
+	binary.Write(&out, binary.BigEndian, uint32(t.buf.Len()))
+	out.Write(t.buf.Bytes())
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// presetKeys returns the keys any region of the preset plays.
+func (h *SoundFontHydra) presetKeys(preset int) ([128]bool, error) {
+	var keys [128]bool
+	regions, err := h.PresetRegions(preset)
+	if err != nil {
+		return keys, err
+	}
+	for _, r := range regions {
+		lo, hi := rangeBytes(r.Gen(Gen_KeyRange))
+		for k := int(lo); k <= int(hi) && k < 128; k++ {
+			keys[k] = true
+		}
+	}
+	return keys, nil
+}
+
+// TestSequence writes a short Standard MIDI File for auditioning the preset
+// at index preset in any player with the bank loaded. It selects the preset
+// with bank select (CC0 holds the bank, CC32 is 0) and a program change.
+//
+// Melodic presets get a major scale up and down an octave near middle C
+// inside the keys the preset plays, a I-IV-V-I cadence, and one note at
+// rising velocities to expose velocity layers. Bank 128 kits play on channel
+// 10 and strike every key the kit maps, in turn.
+func (sf *SoundFont) TestSequence(w io.Writer, preset int) error {
+	keys, err := sf.Hydra.presetKeys(preset)
+	if err != nil {
+		return err
+	}
+	h := sf.Hydra.Headers[preset]
+
+	t := &smfTrack{}
+	t.meta(0, 0x03, []byte(trimName(h.PresetName)))
+	t.meta(0, 0x51, []byte{0x07, 0xa1, 0x20}) // 120 bpm
+
+	const quarter = smfDivision
+	if h.Bank == 128 {
+		const ch = 9
+		t.event(0, 0xc0|ch, byte(h.Preset&0x7f))
+		tick := quarter
+		for k := 0; k < 128; k++ {
+			if keys[k] {
+				t.note(ch, tick, quarter/2, uint8(k), 100)
+				tick += quarter / 2
+			}
+		}
+		return t.writeTo(w)
+	}
+
+	const ch = 0
+	t.event(0, 0xb0|ch, 0, byte(h.Bank&0x7f))
+	t.event(0, 0xb0|ch, 32, 0)
+	t.event(0, 0xc0|ch, byte(h.Preset&0x7f))
+
+	// the C closest to middle C whose octave and chords the preset covers,
+	// else the lowest key it plays
+	root := -1
+	for _, c := range []int{60, 48, 72, 36, 84, 24, 96, 12, 108, 0} {
+		if keys[c] && keys[c+12] {
+			root = c
+			break
+		}
+	}
+	if root < 0 {
+		for k := 0; k < 128; k++ {
+			if keys[k] {
+				root = k
+				break
+			}
+		}
+	}
+	if root < 0 {
+		return t.writeTo(w)
+	}
+	key := func(offset int) uint8 {
+		k := root + offset
+		if k > 127 {
+			k = 127
+		}
+		return uint8(k)
+	}
+
+	tick := quarter
+	scale := []int{0, 2, 4, 5, 7, 9, 11, 12, 11, 9, 7, 5, 4, 2, 0}
+	for _, s := range scale {
+		t.note(ch, tick, quarter, key(s), 100)
+		tick += quarter
+	}
+
+	tick += quarter
+	for _, c := range [][]int{{0, 4, 7}, {5, 9, 12}, {7, 11, 14}, {0, 4, 7, 12}} {
+		var ks []uint8
+		for _, s := range c {
+			ks = append(ks, key(s))
+		}
+		t.chord(ch, tick, 2*quarter, 90, ks...)
+		tick += 2 * quarter
+	}
+
+	tick += quarter
+	for vel := 16; vel <= 128; vel += 16 {
+		v := vel
+		if v > 127 {
+			v = 127
+		}
+		t.note(ch, tick, quarter/2, key(0), uint8(v))
+		tick += quarter
+	}
+	return t.writeTo(w)
+}
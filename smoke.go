@@ -0,0 +1,171 @@
+package sf
+
+import "math"
+
+// SmokeProblem is something wrong SmokeTest heard in a preset.
+type SmokeProblem int
+
+const (
+	// Smoke_NaN is a note whose output held NaN or infinite values.
+	Smoke_NaN SmokeProblem = iota
+	// Smoke_Silent is a note that stayed below -60 dBFS, including presets
+	// that play no regions at all.
+	Smoke_Silent
+	// Smoke_Clipping is a note whose output went past full scale.
+	Smoke_Clipping
+	// Smoke_Runaway is a note more than 6 dB past full scale, usually
+	// stacked layers or negative attenuation rather than a hot sample.
+	Smoke_Runaway
+)
+
+func (p SmokeProblem) String() string {
+	switch p {
+	case Smoke_NaN:
+		return "NaN"
+	case Smoke_Silent:
+		return "silent"
+	case Smoke_Clipping:
+		return "clipping"
+	case Smoke_Runaway:
+		return "runaway level"
+	}
+	return "unknown"
+}
+
+// SmokeResult is what SmokeTest found for one preset.
+type SmokeResult struct {
+	Preset int
+	Name   string
+
+	// Key is the note played, at velocity 127.
+	Key uint8
+	// Peak is the loudest frame of either channel, 1 being full scale.
+	Peak     float64
+	Problems []SmokeProblem
+}
+
+// Passed reports whether the preset played without problems.
+func (r SmokeResult) Passed() bool {
+	return len(r.Problems) == 0
+}
+
+// The note SmokeTest plays: rendered for smokeLength seconds at smokeRate
+// and released after smokeHold seconds.
+const (
+	smokeRate   = 44100
+	smokeLength = 0.5
+	smokeHold   = 0.3
+)
+
+// smokeNote renders the note the regions were resolved for, in stereo. It is
+// a rough render, enough to judge levels: each region reads its sample with
+// linear interpolation, shaped by its volume envelope and attenuation, and is
+// panned with the -3 dB law, so the two sides of a stereo pair land in their
+// own channels rather than adding up. The filter, LFOs and modulators are not
+// applied, and ROM samples are skipped.
+func (sf *SoundFont) smokeNote(regions []Region) (left, right []float64) {
+	left = make([]float64, int(smokeLength*smokeRate))
+	right = make([]float64, len(left))
+	if sf.Samples == nil {
+		return left, right
+	}
+	data := sf.Samples.SamplesHigher
+
+	for i := range regions {
+		r := &regions[i]
+		if r.Sample == nil || r.Sample.isROM() {
+			continue
+		}
+		pb := NewPlayback(r)
+		step := PhaseIncrement(r, r.Key, smokeRate)
+		env := r.VolumeEnvelope()
+		gain := centibelsToGain(float64(r.Gen(Gen_InitialAttenuation)))
+		gl, gr := r.PanGains(PanLaw_3dB, 1)
+
+		released := false
+		for f := range left {
+			t := float64(f) / smokeRate
+			if t >= smokeHold && !released {
+				pb.Release()
+				released = true
+			}
+			pos, ok := pb.Next(step)
+			if !ok {
+				break
+			}
+			j := int(pos)
+			if j < 0 || j >= len(data) {
+				break
+			}
+			v := float64(data[j])
+			if j+1 < len(data) {
+				frac := pos - float64(j)
+				v += frac * (float64(data[j+1]) - v)
+			}
+			v *= gain * env.Level(t, smokeHold) / 32768
+			left[f] += v * gl
+			right[f] += v * gr
+		}
+	}
+	return left, right
+}
+
+// SmokeTest plays one short note on every preset and checks the output for
+// NaNs, silence, clipping and runaway levels, a quick automated check before
+// publishing a bank. The note is the preset's key closest to middle C, held
+// for 300 ms at full velocity; see smokeNote for what the render leaves out.
+func SmokeTest(sf *SoundFont) ([]SmokeResult, error) {
+	var results []SmokeResult
+	for p := 0; p+1 < len(sf.Hydra.Headers); p++ {
+		res := SmokeResult{Preset: p, Name: trimName(sf.Hydra.Headers[p].PresetName)}
+
+		keys, err := sf.Hydra.presetKeys(p)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for d := 0; d < 128 && !found; d++ {
+			for _, k := range []int{60 - d, 60 + d} {
+				if k >= 0 && k < 128 && keys[k] {
+					res.Key, found = uint8(k), true
+					break
+				}
+			}
+		}
+		if !found {
+			res.Problems = append(res.Problems, Smoke_Silent)
+			results = append(results, res)
+			continue
+		}
+
+		regions, err := sf.Hydra.Regions(p, res.Key, 127)
+		if err != nil {
+			return nil, err
+		}
+		nan := false
+		left, right := sf.smokeNote(regions)
+		for _, ch := range [][]float64{left, right} {
+			for _, v := range ch {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					nan = true
+					continue
+				}
+				res.Peak = math.Max(res.Peak, math.Abs(v))
+			}
+		}
+
+		if nan {
+			res.Problems = append(res.Problems, Smoke_NaN)
+		}
+		switch {
+		case res.Peak < 0.001:
+			res.Problems = append(res.Problems, Smoke_Silent)
+		case res.Peak > 2:
+			res.Problems = append(res.Problems, Smoke_Clipping, Smoke_Runaway)
+		case res.Peak > 1:
+			res.Problems = append(res.Problems, Smoke_Clipping)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
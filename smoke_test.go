@@ -0,0 +1,48 @@
+package sf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSmokeTestStereoPair(t *testing.T) {
+	wave := make([]int16, synthLength)
+	for n := range wave {
+		wave[n] = int16(30000 * math.Sin(2*math.Pi*float64(n)/synthPeriod))
+	}
+
+	l := &Layout{}
+	pool := &samplePool{}
+	left, right := loopedHeader("left"), loopedHeader("right")
+	left.SampleType, left.SampleLink = SampleType_Left, 1
+	right.SampleType, right.SampleLink = SampleType_Right, 0
+	l.Samples = append(l.Samples, pool.add(left, wave, nil), pool.add(right, wave, nil))
+
+	zone := func(pan int16, sample int) Zone {
+		return Zone{Generators: []Generator{
+			{GenOper: Gen_Pan, GenAmount: GenAmount(pan)},
+			{GenOper: Gen_SampleModes, GenAmount: GenAmount(SampleMode_Continuous)},
+			{GenOper: Gen_SampleID, GenAmount: GenAmount(sample)},
+		}}
+	}
+	l.Instruments = append(l.Instruments, InstrumentData{
+		Name:  fixedName("stereo"),
+		Zones: []Zone{zone(-500, 0), zone(500, 1)},
+	})
+	l.Presets = append(l.Presets, PresetData{
+		Header: PresetHeader{PresetName: fixedName("stereo")},
+		Zones:  []Zone{{Generators: []Generator{{GenOper: Gen_Instrument, GenAmount: 0}}}},
+	})
+	bank := &SoundFont{Info: newTestInfo("Stereo"), Samples: pool.samples(), Hydra: l.mustPack()}
+
+	results, err := SmokeTest(bank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if r := results[0]; !r.Passed() || r.Peak < 0.8 || r.Peak > 1 {
+		t.Errorf("stereo pair near full scale gave peak %.2f and problems %v", r.Peak, r.Problems)
+	}
+}
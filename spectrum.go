@@ -0,0 +1,118 @@
+package sf
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Window returns the coefficients of an analysis window of length n.
+type Window func(n int) []float64
+
+// Analysis windows for Spectrum and Spectrogram.
+var (
+	// HannWindow is a good default with low leakage.
+	HannWindow Window = hann
+
+	// RectangularWindow applies no window, for transients and exact bins.
+	RectangularWindow Window = func(n int) []float64 {
+		w := make([]float64, n)
+		for i := range w {
+			w[i] = 1
+		}
+		return w
+	}
+
+	// BlackmanWindow trades a wider main lobe for lower side lobes than Hann.
+	BlackmanWindow Window = func(n int) []float64 {
+		w := make([]float64, n)
+		for i := range w {
+			x := 2 * math.Pi * float64(i) / float64(n)
+			w[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		}
+		return w
+	}
+)
+
+// nextPow2 returns the smallest power of two at least n.
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// spectrumFrame windows the data points of frame (up to its length, the rest
+// is zero padding) and returns the scaled magnitudes of the first half of its
+// spectrum. A full scale sine peaks at about 1.
+func spectrumFrame(frame []float64, n int, window []float64) []float64 {
+	x := make([]complex128, n)
+	var gain float64
+	for i, w := range window {
+		if i < len(frame) {
+			x[i] = complex(frame[i]*w, 0)
+		}
+		gain += w
+	}
+	fft(x)
+
+	mags := make([]float64, n/2+1)
+	for i := range mags {
+		re, im := real(x[i]), imag(x[i])
+		mags[i] = 2 * math.Sqrt(re*re+im*im) / gain
+	}
+	return mags
+}
+
+// Spectrum returns the magnitude spectrum of a whole sample, windowed as one
+// frame and zero padded to a power of two. Bin i is at BinFrequency(i, ...).
+func Spectrum(data []int16, window Window) []float64 {
+	if len(data) == 0 {
+		return nil
+	}
+	frame := make([]float64, len(data))
+	for i, v := range data {
+		frame[i] = float64(v) / 32768
+	}
+	return spectrumFrame(frame, nextPow2(len(data)), window(len(data)))
+}
+
+// Spectrogram returns the magnitude spectra of successive frames of a sample,
+// frameSize data points long (rounded up to a power of two) and hop apart.
+// The last partial frame is zero padded. A sample shorter than a frame gives
+// one frame.
+func Spectrogram(data []int16, frameSize, hop int, window Window) [][]float64 {
+	if len(data) == 0 || frameSize <= 0 {
+		return nil
+	}
+	frameSize = nextPow2(frameSize)
+	if hop <= 0 {
+		hop = frameSize / 2
+	}
+
+	w := window(frameSize)
+	frame := make([]float64, frameSize)
+	var frames [][]float64
+	for start := 0; start == 0 || start < len(data); start += hop {
+		n := copyFrame(frame, data[start:])
+		frames = append(frames, spectrumFrame(frame[:n], frameSize, w))
+		if start+frameSize >= len(data) {
+			break
+		}
+	}
+	return frames
+}
+
+// copyFrame converts data points into frame and returns how many were copied.
+func copyFrame(frame []float64, data []int16) int {
+	n := 0
+	for ; n < len(frame) && n < len(data); n++ {
+		frame[n] = float64(data[n]) / 32768
+	}
+	return n
+}
+
+// BinFrequency returns the frequency in Hz of bin i of a spectrum computed
+// with an FFT of length n on audio at sampleRate. n is 2*(len(spectrum)-1).
+func BinFrequency(i, n int, sampleRate uint32) float64 {
+	return float64(i) * float64(sampleRate) / float64(n)
+}
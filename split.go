@@ -0,0 +1,156 @@
+package sf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Extract returns a new SoundFont holding only the given presets, indices
+// into Hydra.Headers, with just the instruments and samples they use. Linked
+// samples, such as the other side of a stereo pair, come along too. The INFO
+// chunk is copied.
+func (sf *SoundFont) Extract(presets []int) (*SoundFont, error) {
+	l, err := sf.Hydra.Unpack()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Layout{}
+	instIndex := map[int]int{}
+	sampleIndex := map[int]int{}
+	var sampleOrder []int
+
+	var addSample func(i int)
+	addSample = func(i int) {
+		if _, ok := sampleIndex[i]; ok || i >= len(l.Samples) {
+			return
+		}
+		sampleIndex[i] = len(sampleOrder)
+		sampleOrder = append(sampleOrder, i)
+		if l.Samples[i].SampleType&^0x8000 != SampleType_Mono {
+			addSample(int(l.Samples[i].SampleLink))
+		}
+	}
+
+	addInst := func(i int) {
+		if _, ok := instIndex[i]; ok || i >= len(l.Instruments) {
+			return
+		}
+		instIndex[i] = len(out.Instruments)
+		inst := InstrumentData{Name: l.Instruments[i].Name}
+		for _, z := range l.Instruments[i].Zones {
+			z = copyZone(z)
+			for k, g := range z.Generators {
+				if g.GenOper == Gen_SampleID {
+					addSample(int(uint16(g.GenAmount)))
+					z.Generators[k].GenAmount = GenAmount(sampleIndex[int(uint16(g.GenAmount))])
+				}
+			}
+			inst.Zones = append(inst.Zones, z)
+		}
+		out.Instruments = append(out.Instruments, inst)
+	}
+
+	for _, i := range presets {
+		if i < 0 || i >= len(l.Presets) {
+			return nil, fmt.Errorf("preset %d out of range", i)
+		}
+		p := PresetData{Header: l.Presets[i].Header}
+		for _, z := range l.Presets[i].Zones {
+			z = copyZone(z)
+			for k, g := range z.Generators {
+				if g.GenOper == Gen_Instrument {
+					addInst(int(uint16(g.GenAmount)))
+					z.Generators[k].GenAmount = GenAmount(instIndex[int(uint16(g.GenAmount))])
+				}
+			}
+			p.Zones = append(p.Zones, z)
+		}
+		out.Presets = append(out.Presets, p)
+	}
+
+	pool := &samplePool{}
+	for _, i := range sampleOrder {
+		s := l.Samples[i]
+		higher, lower := sf.SampleData(s)
+		s = pool.add(s, higher, lower)
+		if j, ok := sampleIndex[int(s.SampleLink)]; ok {
+			s.SampleLink = uint16(j)
+		} else {
+			s.SampleLink = 0
+		}
+		out.Samples = append(out.Samples, s)
+	}
+
+	hydra, err := out.Pack()
+	if err != nil {
+		return nil, err
+	}
+	result := &SoundFont{Hydra: hydra, Samples: pool.samples()}
+	if sf.Info != nil {
+		info := *sf.Info
+		result.Info = &info
+	}
+	return result, nil
+}
+
+// SplitMode is how Split divides a SoundFont.
+type SplitMode int
+
+const (
+	// Split_ByBank makes one SoundFont per bank number.
+	Split_ByBank SplitMode = iota
+	// Split_ByPreset makes one SoundFont per preset.
+	Split_ByPreset
+)
+
+// SplitPart is one SoundFont made by Split.
+type SplitPart struct {
+	// Name describes the part, such as "bank 8" or "000-024 Nylon Guitar".
+	// NamePolicy turns it into a file name.
+	Name      string
+	SoundFont *SoundFont
+}
+
+// Split divides a SoundFont into self-contained parts, each with only the
+// instruments and samples its presets use. It is the inverse of merging the
+// parts back together. Parts are ordered by bank, then program.
+func Split(sf *SoundFont, mode SplitMode) ([]SplitPart, error) {
+	var order []int
+	for i := 0; i+1 < len(sf.Hydra.Headers); i++ {
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ha, hb := sf.Hydra.Headers[order[a]], sf.Hydra.Headers[order[b]]
+		if ha.Bank != hb.Bank {
+			return ha.Bank < hb.Bank
+		}
+		return ha.Preset < hb.Preset
+	})
+
+	var groups [][]int
+	var names []string
+	for _, i := range order {
+		h := sf.Hydra.Headers[i]
+		switch {
+		case mode == Split_ByPreset:
+			groups = append(groups, []int{i})
+			names = append(names, fmt.Sprintf("%03d-%03d %s", h.Bank, h.Preset, trimName(h.PresetName)))
+		case len(groups) == 0 || sf.Hydra.Headers[groups[len(groups)-1][0]].Bank != h.Bank:
+			groups = append(groups, []int{i})
+			names = append(names, fmt.Sprintf("bank %d", h.Bank))
+		default:
+			groups[len(groups)-1] = append(groups[len(groups)-1], i)
+		}
+	}
+
+	parts := make([]SplitPart, len(groups))
+	for g, presets := range groups {
+		part, err := sf.Extract(presets)
+		if err != nil {
+			return nil, err
+		}
+		parts[g] = SplitPart{names[g], part}
+	}
+	return parts, nil
+}
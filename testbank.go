@@ -0,0 +1,166 @@
+package sf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// The synthetic waveforms play at 44 kHz so that a 440 Hz cycle is exactly 100
+// data points long and loops cleanly.
+const (
+	synthRate   = 44000
+	synthPeriod = 100
+	synthLength = 21 * synthPeriod
+)
+
+// newTestInfo returns the INFO data shared by the generated banks.
+func newTestInfo(name string) *SoundFontInfo {
+	info := &SoundFontInfo{Engine: "EMU8000", Name: name, Software: "sf"}
+	info.SfVersion.Major, info.SfVersion.Minor = 2, 1
+	return info
+}
+
+// loopedHeader returns the header of a synthetic sample: an A4 whose last
+// ten cycles form the loop.
+func loopedHeader(name string) SampleHeader {
+	return SampleHeader{
+		SampleName:    fixedName(name),
+		End:           synthLength,
+		Startloop:     synthLength - 11*synthPeriod,
+		Endloop:       synthLength - synthPeriod,
+		SampleRate:    synthRate,
+		OriginalPitch: 69,
+		SampleType:    SampleType_Mono,
+	}
+}
+
+// singleZoneBank builds a bank where preset i plays instrument i, which plays
+// sample i across the whole keyboard, looping.
+func singleZoneBank(info *SoundFontInfo, names []string, data [][]int16) *SoundFont {
+	l := &Layout{}
+	pool := &samplePool{}
+	for i, name := range names {
+		l.Samples = append(l.Samples, pool.add(loopedHeader(name), data[i], nil))
+		l.Instruments = append(l.Instruments, InstrumentData{
+			Name: fixedName(name),
+			Zones: []Zone{{Generators: []Generator{
+				{GenOper: Gen_SampleModes, GenAmount: GenAmount(SampleMode_Continuous)},
+				{GenOper: Gen_SampleID, GenAmount: GenAmount(i)},
+			}}},
+		})
+		l.Presets = append(l.Presets, PresetData{
+			Header: PresetHeader{PresetName: fixedName(name), Preset: uint16(i % 128), Bank: uint16(i / 128)},
+			Zones:  []Zone{{Generators: []Generator{{GenOper: Gen_Instrument, GenAmount: GenAmount(i)}}}},
+		})
+	}
+
+	return &SoundFont{Info: info, Samples: pool.samples(), Hydra: l.mustPack()}
+}
+
+// GenerateSineBank returns a bank of the given number of presets, each playing
+// its own looped wave. Preset i has program i%128 in bank i/128, and its wave
+// sums i%16+1 harmonics of decreasing level so neighboring presets sound
+// distinct.
+// The output is fully deterministic.
+func GenerateSineBank(presets int) *SoundFont {
+	names := make([]string, presets)
+	data := make([][]int16, presets)
+	for i := range names {
+		names[i] = fmt.Sprintf("Sine %d", i)
+		harmonics := i%16 + 1
+
+		d := make([]int16, synthLength)
+		for n := range d {
+			var v float64
+			for h := 1; h <= harmonics; h++ {
+				v += math.Sin(2*math.Pi*float64(h*n)/synthPeriod) / float64(h)
+			}
+			d[n] = int16(v / 2 * 16000)
+		}
+		data[i] = d
+	}
+
+	return singleZoneBank(newTestInfo("Sine Bank"), names, data)
+}
+
+// GenerateNoiseBank returns a bank of the given number of presets, each playing
+// its own looped burst of white noise drawn from seed. The same arguments
+// always produce the same bank.
+func GenerateNoiseBank(presets int, seed int64) *SoundFont {
+	rng := rand.New(rand.NewSource(seed))
+
+	names := make([]string, presets)
+	data := make([][]int16, presets)
+	for i := range names {
+		names[i] = fmt.Sprintf("Noise %d", i)
+		d := make([]int16, synthLength)
+		for n := range d {
+			d[n] = int16(rng.Intn(32000) - 16000)
+		}
+		data[i] = d
+	}
+
+	return singleZoneBank(newTestInfo("Noise Bank"), names, data)
+}
+
+// GeneratePathologicalBank returns a bank that is legal but sits on every
+// boundary a parser or writer is likely to get wrong:
+//   - INFO strings of odd length, of the maximum length, and empty
+//   - names that fill all 20 bytes with no terminator
+//   - a zero length sample and a sample whose loop spans the whole sample
+//   - an instrument with as many zones as the 16-bit generator indices allow
+//   - single key and single velocity ranges at both ends of 0-127
+//   - a preset in bank 128 (percussion) and one at program 127
+func GeneratePathologicalBank() *SoundFont {
+	info := newTestInfo("Odd")
+	info.Copyright = strings.Repeat("c", 255)
+	info.Comments = strings.Repeat("x", 65535)
+	info.Engineers = ""
+	info.Product = "P"
+
+	l := &Layout{}
+	pool := &samplePool{}
+
+	wave := make([]int16, synthLength)
+	for n := range wave {
+		wave[n] = int16(8000 * math.Sin(2*math.Pi*float64(n)/synthPeriod))
+	}
+
+	empty := loopedHeader("ABCDEFGHIJKLMNOPQRST")
+	empty.End, empty.Startloop, empty.Endloop = 0, 0, 0
+	l.Samples = append(l.Samples, pool.add(empty, nil, nil))
+
+	whole := loopedHeader("whole loop")
+	whole.Startloop, whole.Endloop = 0, synthLength
+	l.Samples = append(l.Samples, pool.add(whole, wave, nil))
+
+	// every zone needs keyRange, velRange and sampleID, and the last generator
+	// index (the terminal) must still fit in 16 bits
+	const gensPerZone = 3
+	zones := (math.MaxUint16 - 1) / gensPerZone
+	many := InstrumentData{Name: fixedName("many zones 012345678")}
+	for z := 0; z < zones; z++ {
+		key := z % 128
+		vel := (z / 128) % 128
+		many.Zones = append(many.Zones, Zone{Generators: []Generator{
+			{GenOper: Gen_KeyRange, GenAmount: RangeAmount(uint8(key), uint8(key))},
+			{GenOper: Gen_VelRange, GenAmount: RangeAmount(uint8(vel), uint8(vel))},
+			{GenOper: Gen_SampleID, GenAmount: GenAmount(z % 2)},
+		}})
+	}
+	l.Instruments = append(l.Instruments, many)
+
+	for _, p := range []PresetHeader{
+		{PresetName: fixedName("Percussion 0123456789"), Preset: 0, Bank: 128},
+		{PresetName: fixedName("Last"), Preset: 127, Bank: 0},
+	} {
+		l.Presets = append(l.Presets, PresetData{
+			Header: p,
+			Zones:  []Zone{{Generators: []Generator{{GenOper: Gen_Instrument, GenAmount: 0}}}},
+		})
+	}
+
+	return &SoundFont{Info: info, Samples: pool.samples(), Hydra: l.mustPack()}
+}
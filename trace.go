@@ -0,0 +1,52 @@
+package sf
+
+import "sync"
+
+// Tracer starts spans around the phases of loading a SoundFont, so time can
+// be attributed to chunk parsing or sample decoding. It can be backed by
+// OpenTelemetry or a simple logger. Implementations must be safe for
+// concurrent use.
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is one traced phase. End is called exactly once, with the error the
+// phase failed with, if any.
+type Span interface {
+	End(err error)
+}
+
+// Span names used by the package. Phase spans nest inside SpanLoad.
+const (
+	SpanLoad  = "sf.load"
+	SpanInfo  = "sf.load.info"
+	SpanSdta  = "sf.load.sdta"
+	SpanHydra = "sf.load.pdta"
+)
+
+type nopTracer struct{}
+
+func (nopTracer) Start(string) Span { return nopTracer{} }
+func (nopTracer) End(error)         {}
+
+var (
+	tracerMu sync.RWMutex
+	tracer   Tracer = nopTracer{}
+)
+
+// SetTracer sends the package's spans to t. A nil t turns tracing off, which
+// is the default.
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = nopTracer{}
+	}
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	tracer = t
+}
+
+func startSpan(name string) Span {
+	tracerMu.RLock()
+	defer tracerMu.RUnlock()
+	return tracer.Start(name)
+}
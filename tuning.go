@@ -0,0 +1,135 @@
+package sf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tuning retunes keys away from 12-tone equal temperament. It is applied on
+// top of the SoundFont's own tuning generators.
+type Tuning struct {
+	// Master shifts every key, in cents, after the scale is applied: 100
+	// raises the whole instrument a semitone, and tuning A to 432 Hz is
+	// about -31.8. A zero Tuning with only Master set is a plain master
+	// tuning. See SetMasterTuning.
+	Master float64
+
+	// RefKey is the key the scale starts from. It keeps its equal tempered
+	// pitch. Defaults to 60 in LoadScala.
+	RefKey int
+
+	// Description is the description line of a Scala file.
+	Description string
+
+	// degrees are the scale's pitches in cents above RefKey, the last one
+	// being the period the scale repeats at (usually 1200).
+	degrees []float64
+}
+
+// LoadScala parses a Scala (.scl) scale file. Scale degrees are mapped to
+// consecutive keys starting at key 60.
+func LoadScala(r io.Reader) (*Tuning, error) {
+	t := &Tuning{RefKey: 60}
+
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("scala: missing description or note count")
+	}
+
+	t.Description = strings.TrimSpace(lines[0])
+	count, err := strconv.Atoi(strings.TrimSpace(lines[1]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("scala: invalid note count %q", strings.TrimSpace(lines[1]))
+	}
+	if len(lines)-2 < count {
+		return nil, fmt.Errorf("scala: expected %d pitches, found %d", count, len(lines)-2)
+	}
+
+	for _, line := range lines[2 : 2+count] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("scala: empty pitch line")
+		}
+		cents, err := parseScalaPitch(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		t.degrees = append(t.degrees, cents)
+	}
+
+	if t.degrees[len(t.degrees)-1] <= 0 {
+		return nil, fmt.Errorf("scala: period must be above the unison")
+	}
+	return t, nil
+}
+
+// parseScalaPitch converts a Scala pitch, either cents (containing a '.') or
+// a ratio such as 3/2 or 2, to cents.
+func parseScalaPitch(s string) (float64, error) {
+	if strings.Contains(s, ".") {
+		cents, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("scala: invalid cents value %q", s)
+		}
+		return cents, nil
+	}
+
+	num, den := s, "1"
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		num, den = s[:i], s[i+1:]
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return 0, fmt.Errorf("scala: invalid ratio %q", s)
+	}
+	return 1200 * math.Log2(n/d), nil
+}
+
+// SetMasterTuning sets the shift applied to every key, in cents, see Master.
+func (t *Tuning) SetMasterTuning(cents float64) {
+	t.Master = cents
+}
+
+// Offset returns how many cents key is moved away from its equal tempered
+// pitch, including the master tuning.
+func (t *Tuning) Offset(key int) float64 {
+	if t == nil {
+		return 0
+	}
+	if len(t.degrees) == 0 {
+		return t.Master
+	}
+
+	n := len(t.degrees)
+	period := t.degrees[n-1]
+	steps := key - t.RefKey
+	octave := int(math.Floor(float64(steps) / float64(n)))
+	degree := steps - octave*n
+
+	cents := float64(octave) * period
+	if degree > 0 {
+		cents += t.degrees[degree-1]
+	}
+	return cents - float64(steps)*100 + t.Master
+}
+
+// TunedPitchCents is PitchCents with the tuning's offset for key applied.
+func TunedPitchCents(r *Region, key uint8, t *Tuning) float64 {
+	return PitchCents(r, key) + t.Offset(int(key))
+}
@@ -0,0 +1,41 @@
+package sf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTuningOffset(t *testing.T) {
+	fifths, err := LoadScala(strings.NewReader("! pythagorean fragment\nfifths\n2\n3/2\n2/1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tuning *Tuning
+		master float64
+		key    int
+		want   float64
+	}{
+		{"nil", nil, 0, 64, 0},
+		{"master only", &Tuning{}, 100, 64, 100},
+		{"A432", &Tuning{}, 1200 * math.Log2(432.0/440), 69, -31.77},
+		{"scale reference key", fifths, 0, 60, 0},
+		{"scale degree", fifths, 0, 61, 1200*math.Log2(1.5) - 100},
+		{"scale next period", fifths, 0, 62, 1200 - 200},
+		{"scale below reference", fifths, 0, 59, 1200*math.Log2(1.5) - 1200 + 100},
+		{"scale and master", fifths, -50, 62, 1000 - 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tuning != nil {
+				tt.tuning.SetMasterTuning(tt.master)
+			}
+			if got := tt.tuning.Offset(tt.key); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("Offset(%d) = %.2f, want %.2f", tt.key, got, tt.want)
+			}
+		})
+	}
+}
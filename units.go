@@ -0,0 +1,44 @@
+package sf
+
+import "math"
+
+// timecentsToSeconds converts an absolute timecent generator amount, the
+// minimum -32768 meaning instantaneous.
+func timecentsToSeconds(tc int) float64 {
+	if tc <= -32768 {
+		return 0
+	}
+	return math.Pow(2, float64(tc)/1200)
+}
+
+// absoluteCentsToHz converts an absolute cent frequency, 0 being 8.176 Hz.
+func absoluteCentsToHz(c int) float64 {
+	return 8.176 * math.Pow(2, float64(c)/1200)
+}
+
+// centibelsToGain converts an attenuation in centibels to a linear gain.
+func centibelsToGain(cb float64) float64 {
+	return math.Pow(10, -cb/200)
+}
+
+// secondsToTimecents is the inverse of timecentsToSeconds, 0 seconds giving
+// the instantaneous -32768.
+func secondsToTimecents(s float64) int16 {
+	if s <= 0 {
+		return -32768
+	}
+	return clampInt16(int(math.Round(1200 * math.Log2(s))))
+}
+
+// hzToAbsoluteCents is the inverse of absoluteCentsToHz.
+func hzToAbsoluteCents(hz float64) int16 {
+	return clampInt16(int(math.Round(1200 * math.Log2(hz/8.176))))
+}
+
+// gainToCentibels is the inverse of centibelsToGain, silence giving 1440 cB.
+func gainToCentibels(gain float64) float64 {
+	if gain <= 0 {
+		return 1440
+	}
+	return -200 * math.Log10(gain)
+}
@@ -0,0 +1,61 @@
+package sf
+
+import "fmt"
+
+// Problem is a single spec violation found by Validate.
+type Problem struct {
+	// Rule is a short stable identifier of the violated rule.
+	Rule string
+	// Where locates the problem, e.g. "preset 3 zone 1".
+	Where string
+	// Message describes the problem.
+	Message string
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", p.Where, p.Message, p.Rule)
+}
+
+// Validate checks the hydra for violations of the spec that the parser itself
+// tolerates, returning every problem found.
+func (h *SoundFontHydra) Validate() []Problem {
+	var problems []Problem
+
+	for i := 0; i+1 < len(h.Headers); i++ {
+		zones, err := h.PresetZones(i)
+		if err != nil {
+			problems = append(problems, Problem{"bad-zone-index", fmt.Sprintf("preset %d", i), err.Error()})
+			continue
+		}
+		for z, zone := range zones {
+			where := fmt.Sprintf("preset %d zone %d", i, z)
+			problems = append(problems, validateRanges(where, zone)...)
+			problems = append(problems, validateOrder(where, zone)...)
+			for _, g := range zone.Generators {
+				if !PresetLegal(g.GenOper) {
+					problems = append(problems, Problem{"illegal-preset-generator", where, fmt.Sprintf("generator %d is not allowed at the preset level", g.GenOper)})
+				}
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(h.Instuments); i++ {
+		zones, err := h.InstrumentZones(i)
+		if err != nil {
+			problems = append(problems, Problem{"bad-zone-index", fmt.Sprintf("instrument %d", i), err.Error()})
+			continue
+		}
+		for z, zone := range zones {
+			where := fmt.Sprintf("instrument %d zone %d", i, z)
+			problems = append(problems, validateRanges(where, zone)...)
+			problems = append(problems, validateOrder(where, zone)...)
+			for _, g := range zone.Generators {
+				if !InstrumentLegal(g.GenOper) {
+					problems = append(problems, Problem{"illegal-instrument-generator", where, fmt.Sprintf("generator %d is not allowed at the instrument level", g.GenOper)})
+				}
+			}
+		}
+	}
+
+	return problems
+}
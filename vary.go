@@ -0,0 +1,118 @@
+package sf
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// variationSpread is how far VaryPreset moves each generator at amount 1,
+// in the generator's own units.
+var variationSpread = []struct {
+	op     SFGenerator
+	spread float64
+}{
+	// detune, in cents
+	{Gen_FineTune, 20},
+	// envelopes, 1200 timecents doubles or halves a time
+	{Gen_DelayVolEnv, 600},
+	{Gen_AttackVolEnv, 1200},
+	{Gen_HoldVolEnv, 1200},
+	{Gen_DecayVolEnv, 1200},
+	{Gen_SustainVolEnv, 60},
+	{Gen_ReleaseVolEnv, 1200},
+	{Gen_AttackModEnv, 1200},
+	{Gen_DecayModEnv, 1200},
+	{Gen_SustainModEnv, 200},
+	{Gen_ReleaseModEnv, 1200},
+	// filter, cutoff in cents and resonance in centibels
+	{Gen_InitialFilterFc, 1200},
+	{Gen_InitialFilterQ, 60},
+}
+
+// VaryPreset adds a randomized variation of the preset at index p and
+// returns the new preset's index. Detune, envelope and filter generators each
+// move by up to amount times a musically sized step, 0 giving a copy and 1 a
+// clearly different patch. A change is applied to the whole preset at once,
+// as a preset level offset, and is limited so that every region stays inside
+// the generator's legal range. The variation keeps the preset's name and
+// program and goes to the first bank above it where that program is free. A
+// variation of a percussion kit stays in its bank, at the next free program.
+func (h *SoundFontHydra) VaryPreset(p int, rng *rand.Rand, amount float64) (int, error) {
+	if amount < 0 {
+		return 0, fmt.Errorf("variation amount %g is negative", amount)
+	}
+	regions, err := h.PresetRegions(p)
+	if err != nil {
+		return 0, err
+	}
+	l, err := h.Unpack()
+	if err != nil {
+		return 0, err
+	}
+	oracle := SpecOracle()
+
+	var offsets []Generator
+	for _, v := range variationSpread {
+		delta := int(math.Round((2*rng.Float64() - 1) * v.spread * amount))
+
+		// keep every region's value inside the legal range
+		lo, hi := math.MinInt32, math.MaxInt32
+		if min, max, ok := oracle.Range(v.op); ok {
+			for _, r := range regions {
+				value := int(r.Gen(v.op))
+				if min != nil && *min-value > lo {
+					lo = *min - value
+				}
+				if max != nil && *max-value < hi {
+					hi = *max - value
+				}
+			}
+		}
+		if lo > hi {
+			continue
+		}
+		if delta < lo {
+			delta = lo
+		}
+		if delta > hi {
+			delta = hi
+		}
+		if delta != 0 {
+			offsets = append(offsets, Generator{v.op, GenAmount(delta)})
+		}
+	}
+
+	src := l.Presets[p]
+	header, err := freeSlot(l, src.Header)
+	if err != nil {
+		return 0, err
+	}
+	var global *Zone
+	if len(src.Zones) > 0 {
+		if _, ok := src.Zones[0].terminal(Gen_Instrument); !ok {
+			global = &src.Zones[0]
+		}
+	}
+	variant := PresetData{Header: header}
+	for _, z := range src.Zones {
+		z = copyZone(z)
+		if _, ok := z.terminal(Gen_Instrument); ok {
+			z.Global = global
+			values, _ := zoneValues(&z)
+			z.Global = nil
+			for _, g := range offsets {
+				setGenerator(&z, g.GenOper, clampInt16(int(values[g.GenOper])+int(g.GenAmount)))
+			}
+		}
+		variant.Zones = append(variant.Zones, z)
+	}
+
+	l.Presets = append(l.Presets, variant)
+	packed, err := l.Pack()
+	if err != nil {
+		return 0, err
+	}
+	*h = *packed
+	return len(l.Presets) - 1, nil
+}
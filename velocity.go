@@ -0,0 +1,87 @@
+package sf
+
+import (
+	"math"
+	"sort"
+)
+
+// VelocityCurve reshapes note-on velocities before they reach region
+// resolution and the modulators, to match the response of a keyboard or the
+// expectations of a bank. Entry v is the velocity played for an incoming v.
+type VelocityCurve [128]uint8
+
+// LinearVelocityCurve passes velocities through unchanged.
+func LinearVelocityCurve() VelocityCurve {
+	var c VelocityCurve
+	for v := range c {
+		c[v] = uint8(v)
+	}
+	return c
+}
+
+// PowerVelocityCurve maps v to 127*(v/127)^exponent. Exponents above 1 give a
+// concave curve that needs a harder touch, below 1 a convex curve that makes
+// soft playing louder. Velocity 0 stays 0, and no other velocity drops below 1
+// since 0 means note-off.
+func PowerVelocityCurve(exponent float64) VelocityCurve {
+	var c VelocityCurve
+	for v := 1; v < len(c); v++ {
+		out := math.Round(127 * math.Pow(float64(v)/127, exponent))
+		if out < 1 {
+			out = 1
+		}
+		c[v] = uint8(out)
+	}
+	return c
+}
+
+// VelocityPoint is a point of a custom velocity curve.
+type VelocityPoint struct {
+	In, Out uint8
+}
+
+// TableVelocityCurve builds a curve through the given points, interpolating
+// linearly between them and holding the first and last point's output beyond
+// them. The points need not be sorted. As with PowerVelocityCurve, velocity 0
+// stays 0 and no other velocity drops below 1, whatever the points say.
+func TableVelocityCurve(points ...VelocityPoint) VelocityCurve {
+	if len(points) == 0 {
+		return LinearVelocityCurve()
+	}
+
+	sorted := append([]VelocityPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].In < sorted[j].In })
+
+	var c VelocityCurve
+	for v := range c {
+		i := sort.Search(len(sorted), func(i int) bool { return int(sorted[i].In) >= v })
+		switch {
+		case i == 0:
+			c[v] = sorted[0].Out
+		case i == len(sorted):
+			c[v] = sorted[len(sorted)-1].Out
+		default:
+			a, b := sorted[i-1], sorted[i]
+			t := float64(v-int(a.In)) / float64(int(b.In)-int(a.In))
+			c[v] = uint8(math.Round(float64(a.Out) + t*(float64(b.Out)-float64(a.Out))))
+		}
+		if c[v] > 127 {
+			c[v] = 127
+		}
+		if c[v] < 1 {
+			c[v] = 1
+		}
+	}
+	c[0] = 0
+	return c
+}
+
+// Apply returns the velocity to play for an incoming velocity. Velocities
+// above 127 are treated as 127. Note that the velocity generator overrides the
+// curved velocity, as it does the played one.
+func (c *VelocityCurve) Apply(v uint8) uint8 {
+	if v > 127 {
+		v = 127
+	}
+	return c[v]
+}
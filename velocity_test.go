@@ -0,0 +1,27 @@
+package sf
+
+import "testing"
+
+func TestTableVelocityCurveNoteOff(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []VelocityPoint
+	}{
+		{"raised floor", []VelocityPoint{{0, 40}, {127, 127}}},
+		{"single point", []VelocityPoint{{64, 100}}},
+		{"zero output", []VelocityPoint{{0, 0}, {20, 0}, {127, 127}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := TableVelocityCurve(tt.points...)
+			if c[0] != 0 {
+				t.Errorf("velocity 0 plays as %d, want note-off", c[0])
+			}
+			for v := 1; v < len(c); v++ {
+				if c[v] == 0 {
+					t.Errorf("velocity %d plays as note-off", v)
+				}
+			}
+		})
+	}
+}
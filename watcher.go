@@ -0,0 +1,179 @@
+package sf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchOp is what happened to a file seen by a Watcher.
+type WatchOp int
+
+const (
+	// Watch_Add is a SoundFont that appeared, including those present when
+	// the watcher started.
+	Watch_Add WatchOp = iota
+	// Watch_Update is a SoundFont whose size or modification time changed.
+	Watch_Update
+	// Watch_Remove is a SoundFont that is gone.
+	Watch_Remove
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case Watch_Add:
+		return "add"
+	case Watch_Update:
+		return "update"
+	case Watch_Remove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// WatchEvent reports a change to a file in a watched directory.
+type WatchEvent struct {
+	Op   WatchOp
+	Path string
+
+	// SoundFont is the freshly parsed file for adds and updates, nil when
+	// parsing failed or for removals.
+	SoundFont *SoundFont
+	// Err is why the file could not be loaded. A file still being written
+	// usually fails and is tried again when it next changes.
+	Err error
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher polls a directory for .sf2 files and loads the ones that were
+// added or changed since the last poll. It polls rather than subscribing to
+// the operating system so it works the same everywhere, including on network
+// shares.
+type Watcher struct {
+	// Events delivers changes in the order they were found. It is closed
+	// once the watcher stops.
+	Events <-chan WatchEvent
+
+	dir      string
+	interval time.Duration
+	files    map[string]fileStamp
+	events   chan WatchEvent
+	stop     chan struct{}
+}
+
+// NewWatcher starts watching dir, polling every interval. Files already in
+// the directory are reported as added by the first poll. interval must be
+// positive.
+func NewWatcher(dir string, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("watch interval %v is not positive", interval)
+	}
+	if _, err := os.ReadDir(dir); err != nil {
+		return nil, err
+	}
+	events := make(chan WatchEvent)
+	w := &Watcher{
+		Events:   events,
+		dir:      dir,
+		interval: interval,
+		files:    map[string]fileStamp{},
+		events:   events,
+		stop:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops the watcher. Events is closed once any event being delivered
+// has been received or dropped.
+func (w *Watcher) Close() error {
+	close(w.stop)
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if !w.poll() {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// poll compares the directory with the last poll and sends the differences.
+// It returns false when the watcher was closed.
+func (w *Watcher) poll() bool {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		// the directory may come back, like a remounted share
+		return true
+	}
+
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".sf2") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(w.dir, e.Name())
+		seen[path] = true
+
+		stamp := fileStamp{info.Size(), info.ModTime()}
+		old, known := w.files[path]
+		if known && old == stamp {
+			continue
+		}
+		w.files[path] = stamp
+
+		ev := WatchEvent{Op: Watch_Add, Path: path}
+		if known {
+			ev.Op = Watch_Update
+		}
+		ev.SoundFont, ev.Err = LoadFile(path)
+		if !w.send(ev) {
+			return false
+		}
+	}
+
+	var gone []string
+	for path := range w.files {
+		if !seen[path] {
+			gone = append(gone, path)
+		}
+	}
+	sort.Strings(gone)
+	for _, path := range gone {
+		delete(w.files, path)
+		if !w.send(WatchEvent{Op: Watch_Remove, Path: path}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Watcher) send(ev WatchEvent) bool {
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
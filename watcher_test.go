@@ -0,0 +1,15 @@
+package sf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewWatcherInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if w, err := NewWatcher(t.TempDir(), interval); err == nil {
+			w.Close()
+			t.Errorf("NewWatcher accepted an interval of %v", interval)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package sf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+// Peaks is a waveform overview: the lowest and highest value, in -1..1, of
+// the audio falling in each column.
+type Peaks struct {
+	Min, Max []float32
+}
+
+// SamplePeaks computes the peaks of 16-bit sample data for a given number of
+// columns.
+func SamplePeaks(data []int16, columns int) Peaks {
+	return peaks(len(data), columns, func(i int) float32 { return float32(data[i]) / 32768 })
+}
+
+// AudioPeaks computes the peaks of rendered audio for a given number of
+// columns.
+func AudioPeaks(audio []float32, columns int) Peaks {
+	return peaks(len(audio), columns, func(i int) float32 { return audio[i] })
+}
+
+func peaks(n, columns int, at func(int) float32) Peaks {
+	if columns <= 0 {
+		return Peaks{}
+	}
+	p := Peaks{Min: make([]float32, columns), Max: make([]float32, columns)}
+	if n == 0 {
+		return p
+	}
+	for c := 0; c < columns; c++ {
+		start, end := c*n/columns, (c+1)*n/columns
+		if end <= start {
+			// more columns than points, repeat the nearest point
+			end = start + 1
+		}
+		lo, hi := at(start), at(start)
+		for i := start + 1; i < end && i < n; i++ {
+			v := at(i)
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		p.Min[c], p.Max[c] = lo, hi
+	}
+	return p
+}
+
+// row maps v in -1..1 to a pixel row of an image h pixels high, top down.
+func row(v float32, h int) int {
+	y := int((1 - v) / 2 * float32(h-1))
+	if y < 0 {
+		return 0
+	}
+	if y >= h {
+		return h - 1
+	}
+	return y
+}
+
+// Waveform colors used by Image and SVG.
+var (
+	WaveformBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	WaveformForeground = color.RGBA{0x1f, 0x4e, 0x79, 0xff}
+)
+
+// Image draws the peaks as a w x h image, one column per peak pair when
+// len(p.Min) == w. Encode it with image/png for a thumbnail.
+func (p Peaks) Image(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(WaveformBackground), image.Point{}, draw.Src)
+	if len(p.Min) == 0 || h <= 0 {
+		return img
+	}
+
+	for x := 0; x < w; x++ {
+		c := x * len(p.Min) / w
+		top, bottom := row(p.Max[c], h), row(p.Min[c], h)
+		for y := top; y <= bottom; y++ {
+			img.SetRGBA(x, y, WaveformForeground)
+		}
+	}
+	return img
+}
+
+// SVG writes the peaks as an SVG image w x h units large, a single filled
+// path tracing the maxima left to right and the minima back.
+func (p Peaks) SVG(out io.Writer, w, h int) error {
+	hex := func(c color.RGBA) string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+	if _, err := fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n"+
+		`<rect width="%d" height="%d" fill="%s"/>`+"\n", w, h, w, h, w, h, hex(WaveformBackground)); err != nil {
+		return err
+	}
+
+	n := len(p.Min)
+	if n > 0 {
+		scale := float64(w) / float64(n)
+		y := func(v float32) float64 { return (1 - float64(v)) / 2 * float64(h) }
+		fmt.Fprintf(out, `<path fill="%s" stroke="%s" stroke-width="0.5" d="M0 %.1f`, hex(WaveformForeground), hex(WaveformForeground), y(p.Max[0]))
+		for c := 0; c < n; c++ {
+			fmt.Fprintf(out, " L%.1f %.1f L%.1f %.1f", float64(c)*scale, y(p.Max[c]), float64(c+1)*scale, y(p.Max[c]))
+		}
+		for c := n - 1; c >= 0; c-- {
+			fmt.Fprintf(out, " L%.1f %.1f L%.1f %.1f", float64(c+1)*scale, y(p.Min[c]), float64(c)*scale, y(p.Min[c]))
+		}
+		fmt.Fprint(out, ` Z"/>`+"\n")
+	}
+
+	_, err := fmt.Fprint(out, "</svg>\n")
+	return err
+}
+
+// RenderWaveform draws a sample's waveform as a w x h thumbnail.
+func (sf *SoundFont) RenderWaveform(s SampleHeader, w, h int) *image.RGBA {
+	higher, _ := sf.SampleData(s)
+	return SamplePeaks(higher, w).Image(w, h)
+}
@@ -0,0 +1,400 @@
+package sf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// writeChunk writes a chunk header and its data, followed by the pad byte
+// that keeps the next chunk at an even offset when the data's size is odd.
+// The size recorded in the header does not count the pad byte.
+func writeChunk(w io.Writer, id [4]byte, data []byte) error {
+	header := struct {
+		ID   [4]byte
+		Size uint32
+	}{id, uint32(len(data))}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if len(data)%2 == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// paddedSize is the number of bytes a chunk holding size bytes of data takes
+// in its parent, header and pad byte included.
+func paddedSize(size int64) int64 {
+	return 8 + size + size%2
+}
+
+// infoString encodes an INFO text field: the text without any terminators it
+// was read with, then one or two zero bytes so the size is even. max is the
+// largest size the spec allows, terminators included.
+func infoString(id [4]byte, s string, max int) ([]byte, error) {
+	s = strings.TrimRight(s, "\x00")
+	b := append([]byte(s), 0)
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+	if len(b) > max {
+		return nil, fmt.Errorf("%s subchunk must contain %d or fewer bytes, %q has %d", string(id[:]), max, s, len(b))
+	}
+	return b, nil
+}
+
+// encodeInfo returns the body of the INFO list, its subchunks in the order
+// the spec lists them. The mandatory ifil, isng and INAM are always written,
+// an empty Engine as "EMU8000" and a zero version as 2.01, or 2.04 when there
+// are 24-bit samples. The optional fields are written when set.
+func encodeInfo(info *SoundFontInfo, samples *SoundFontSamples) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("INFO")
+
+	version := info.SfVersion
+	if version.Major == 0 && version.Minor == 0 {
+		version.Major, version.Minor = 2, 1
+		if samples != nil && len(samples.SamplesLower) > 0 {
+			version.Minor = 4
+		}
+	}
+	var ifil bytes.Buffer
+	binary.Write(&ifil, binary.LittleEndian, version)
+	if err := writeChunk(&buf, [4]byte{'i', 'f', 'i', 'l'}, ifil.Bytes()); err != nil {
+		return nil, err
+	}
+
+	engine := info.Engine
+	if strings.TrimRight(engine, "\x00") == "" {
+		engine = "EMU8000"
+	}
+
+	fields := []struct {
+		id       [4]byte
+		value    string
+		max      int
+		required bool
+	}{
+		{[4]byte{'i', 's', 'n', 'g'}, engine, 256, true},
+		{[4]byte{'I', 'N', 'A', 'M'}, info.Name, 256, true},
+		{[4]byte{'i', 'r', 'o', 'm'}, info.ROM, 256, false},
+		{[4]byte{'I', 'C', 'R', 'D'}, info.CreationDate, 256, false},
+		{[4]byte{'I', 'E', 'N', 'G'}, info.Engineers, 256, false},
+		{[4]byte{'I', 'P', 'R', 'D'}, info.Product, 256, false},
+		{[4]byte{'I', 'C', 'O', 'P'}, info.Copyright, 256, false},
+		{[4]byte{'I', 'C', 'M', 'T'}, info.Comments, 65536, false},
+		{[4]byte{'I', 'S', 'F', 'T'}, info.Software, 256, false},
+	}
+	for _, f := range fields {
+		if !f.required && strings.TrimRight(f.value, "\x00") == "" {
+			continue
+		}
+		data, err := infoString(f.id, f.value, f.max)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeChunk(&buf, f.id, data); err != nil {
+			return nil, err
+		}
+
+		// iver goes with irom, right after it
+		if f.id == [4]byte{'i', 'r', 'o', 'm'} {
+			var iver bytes.Buffer
+			binary.Write(&iver, binary.LittleEndian, info.ROMVer)
+			if err := writeChunk(&buf, [4]byte{'i', 'v', 'e', 'r'}, iver.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// checkOrder returns the first zone whose generators are out of the order
+// the spec requires, which the writer will not put in a file.
+func checkOrder(h *SoundFontHydra) error {
+	for i := 0; i+1 < len(h.Headers); i++ {
+		zones, err := h.PresetZones(i)
+		if err != nil {
+			return err
+		}
+		for z, zone := range zones {
+			if problems := validateOrder(fmt.Sprintf("preset %d zone %d", i, z), zone); len(problems) > 0 {
+				return problems[0]
+			}
+		}
+	}
+	for i := 0; i+1 < len(h.Instuments); i++ {
+		zones, err := h.InstrumentZones(i)
+		if err != nil {
+			return err
+		}
+		for z, zone := range zones {
+			if problems := validateOrder(fmt.Sprintf("instrument %d zone %d", i, z), zone); len(problems) > 0 {
+				return problems[0]
+			}
+		}
+	}
+	return nil
+}
+
+// encodeHydra returns the body of the pdta list. Every table is written as
+// it is, so each must already end with its terminal record: EOP, EOI and EOS
+// for the headers, and the final bag, generator and modulator.
+func encodeHydra(h *SoundFontHydra) ([]byte, error) {
+	tables := []struct {
+		id   [4]byte
+		n    int
+		data interface{}
+	}{
+		{[4]byte{'p', 'h', 'd', 'r'}, len(h.Headers), h.Headers},
+		{[4]byte{'p', 'b', 'a', 'g'}, len(h.PBag), h.PBag},
+		{[4]byte{'p', 'm', 'o', 'd'}, len(h.PresetModulators), h.PresetModulators},
+		{[4]byte{'p', 'g', 'e', 'n'}, len(h.PresetGenerators), h.PresetGenerators},
+		{[4]byte{'i', 'n', 's', 't'}, len(h.Instuments), h.Instuments},
+		{[4]byte{'i', 'b', 'a', 'g'}, len(h.IBag), h.IBag},
+		{[4]byte{'i', 'm', 'o', 'd'}, len(h.InstrumentModulators), h.InstrumentModulators},
+		{[4]byte{'i', 'g', 'e', 'n'}, len(h.InstrumentGenerators), h.InstrumentGenerators},
+		{[4]byte{'s', 'h', 'd', 'r'}, len(h.Samples), h.Samples},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("pdta")
+	for _, t := range tables {
+		if t.n == 0 {
+			return nil, fmt.Errorf("%s subchunk has no terminal record", string(t.id[:]))
+		}
+		var data bytes.Buffer
+		if err := binary.Write(&data, binary.LittleEndian, t.data); err != nil {
+			return nil, err
+		}
+		if err := writeChunk(&buf, t.id, data.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteOption changes how WriteSoundFont writes a file.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	verify bool
+}
+
+// WithVerify makes WriteSoundFont parse the file it wrote and compare it with
+// sf before handing any of it to w, so a writer bug is an error at save time
+// rather than a file a player rejects later. The file is held in memory while
+// it is checked.
+func WithVerify() WriteOption {
+	return func(o *writeOptions) { o.verify = true }
+}
+
+// WriteSoundFont writes sf to w as an SF2 file: a RIFF sfbk form holding the
+// INFO, sdta and pdta lists, with every chunk size computed from the data
+// written and odd sized chunks padded. Text fields are re-terminated with one
+// or two zero bytes, so a file read and written back keeps its sizes. The
+// hydra is written as it is, see SoundFontHydra and Layout.Pack for the
+// records it must hold. A zone whose generators are out of the spec's order
+// is an error, ReorderGenerators fixes one.
+//
+// The output depends only on sf: chunks always go in the same order, padding
+// is always zero bytes and nothing such as the time of writing is added, so
+// writing equal SoundFonts gives byte-identical files that can be cached and
+// compared by hash. ICRD is written as it is stored, never filled in.
+func WriteSoundFont(w io.Writer, sf *SoundFont, opts ...WriteOption) error {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.verify {
+		return writeSoundFont(w, sf)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSoundFont(&buf, sf); err != nil {
+		return err
+	}
+	if err := verifyWritten(buf.Bytes(), sf); err != nil {
+		return fmt.Errorf("verifying the written file: %w", err)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// verifyWritten parses data, a file written from sf, and checks it holds
+// what sf does.
+func verifyWritten(data []byte, sf *SoundFont) error {
+	read, err := ReadSoundFont(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	// encoding both is the comparison the writer cares about, terminators
+	// and defaults included
+	samples := sf.Samples
+	if samples == nil {
+		samples = &SoundFontSamples{}
+	}
+	want, err := encodeInfo(sf.Info, samples)
+	if err != nil {
+		return err
+	}
+	got, err := encodeInfo(read.Info, samples)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("INFO list differs")
+	}
+
+	if !equalInt16s(read.Samples.SamplesHigher, samples.SamplesHigher) {
+		return fmt.Errorf("smpl data differs")
+	}
+	if len(read.Samples.SamplesLower) != len(samples.SamplesLower) {
+		return fmt.Errorf("sm24 holds %d sample points, want %d", len(read.Samples.SamplesLower), len(samples.SamplesLower))
+	}
+	for i, b := range samples.SamplesLower {
+		if read.Samples.SamplesLower[i] != b {
+			return fmt.Errorf("sm24 data differs at sample point %d", i)
+		}
+	}
+
+	if !reflect.DeepEqual(read.Hydra, sf.Hydra) {
+		return fmt.Errorf("pdta list differs")
+	}
+	return nil
+}
+
+func equalInt16s(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// chunkSizes works out the sizes of the smpl chunk, the sdta list and the
+// whole RIFF chunk from the sizes of the encoded INFO and hydra lists and the
+// number of sample points. A bank too large for the 32-bit RIFF size is an
+// error.
+func chunkSizes(info, hydra, higher, lower int) (smpl, sdta, riff int64, err error) {
+	smpl = 2 * int64(higher)
+	sdta = 4 + paddedSize(smpl)
+	if lower > 0 {
+		sdta += paddedSize(int64(lower))
+	}
+	riff = 4 + paddedSize(int64(info)) + paddedSize(sdta) + paddedSize(int64(hydra))
+	if riff > math.MaxUint32 {
+		return 0, 0, 0, fmt.Errorf("SoundFont of %d bytes is too large for RIFF", riff)
+	}
+	return smpl, sdta, riff, nil
+}
+
+func writeSoundFont(w io.Writer, sf *SoundFont) error {
+	if sf.Info == nil {
+		return fmt.Errorf("missing INFO")
+	}
+	if sf.Hydra == nil {
+		return fmt.Errorf("missing hydra")
+	}
+	samples := sf.Samples
+	if samples == nil {
+		samples = &SoundFontSamples{}
+	}
+	if len(samples.SamplesLower) > 0 && len(samples.SamplesLower) != len(samples.SamplesHigher) {
+		return fmt.Errorf("sm24 holds %d sample points, smpl holds %d", len(samples.SamplesLower), len(samples.SamplesHigher))
+	}
+
+	if err := checkOrder(sf.Hydra); err != nil {
+		return err
+	}
+	info, err := encodeInfo(sf.Info, samples)
+	if err != nil {
+		return err
+	}
+	hydra, err := encodeHydra(sf.Hydra)
+	if err != nil {
+		return err
+	}
+
+	// the samples are streamed, so the sdta size is worked out up front
+	smplSize, sdtaSize, riffSize, err := chunkSizes(len(info), len(hydra), len(samples.SamplesHigher), len(samples.SamplesLower))
+	if err != nil {
+		return err
+	}
+
+	header := struct {
+		RIFF [4]byte
+		Size uint32
+		SFBK [4]byte
+	}{[4]byte{'R', 'I', 'F', 'F'}, uint32(riffSize), [4]byte{'s', 'f', 'b', 'k'}}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	if err := writeChunk(w, [4]byte{'L', 'I', 'S', 'T'}, info); err != nil {
+		return err
+	}
+
+	sdta := struct {
+		LIST     [4]byte
+		Size     uint32
+		SDTA     [4]byte
+		SMPL     [4]byte
+		SmplSize uint32
+	}{
+		[4]byte{'L', 'I', 'S', 'T'}, uint32(sdtaSize), [4]byte{'s', 'd', 't', 'a'},
+		[4]byte{'s', 'm', 'p', 'l'}, uint32(smplSize),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &sdta); err != nil {
+		return err
+	}
+	if err := writeSmpl(w, samples.SamplesHigher); err != nil {
+		return err
+	}
+	if len(samples.SamplesLower) > 0 {
+		lower := make([]byte, len(samples.SamplesLower))
+		for i, b := range samples.SamplesLower {
+			lower[i] = byte(b)
+		}
+		if err := writeChunk(w, [4]byte{'s', 'm', '2', '4'}, lower); err != nil {
+			return err
+		}
+	}
+
+	return writeChunk(w, [4]byte{'L', 'I', 'S', 'T'}, hydra)
+}
+
+// writeSmpl writes the smpl sample points through a small buffer, so writing
+// a bank does not make a second copy of its sample data in memory.
+func writeSmpl(w io.Writer, points []int16) error {
+	buf := make([]byte, 0, 64<<10)
+	for len(points) > 0 {
+		n := len(points)
+		if n > cap(buf)/2 {
+			n = cap(buf) / 2
+		}
+		buf = buf[:2*n]
+		for i, v := range points[:n] {
+			binary.LittleEndian.PutUint16(buf[2*i:], uint16(v))
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
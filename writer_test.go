@@ -0,0 +1,158 @@
+package sf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteSoundFontRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		bank *SoundFont
+	}{
+		{"sine", GenerateSineBank(8)},
+		{"pathological", GeneratePathologicalBank()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var first bytes.Buffer
+			if err := WriteSoundFont(&first, tt.bank); err != nil {
+				t.Fatal(err)
+			}
+			read, err := ReadSoundFont(bytes.NewReader(first.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(read.Hydra, tt.bank.Hydra) {
+				t.Error("hydra changed in the round trip")
+			}
+			if !reflect.DeepEqual(read.Samples.SamplesHigher, tt.bank.Samples.SamplesHigher) {
+				t.Error("smpl data changed in the round trip")
+			}
+			if len(read.Samples.SamplesLower) != len(tt.bank.Samples.SamplesLower) {
+				t.Error("sm24 data changed in the round trip")
+			}
+			if got, want := strings.TrimRight(read.Info.Comments, "\x00"), tt.bank.Info.Comments; got != want {
+				t.Errorf("ICMT of %d bytes read back as %d", len(want), len(got))
+			}
+
+			var second bytes.Buffer
+			if err := WriteSoundFont(&second, read); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Errorf("writing the file read back gives %d different bytes from the %d first written", second.Len(), first.Len())
+			}
+		})
+	}
+}
+
+func TestWriteSoundFontGeneratorOrder(t *testing.T) {
+	bank := GenerateSineBank(1)
+	l, err := bank.Hydra.Unpack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gens := l.Instruments[0].Zones[0].Generators
+	gens[0], gens[len(gens)-1] = gens[len(gens)-1], gens[0]
+	if bank.Hydra, err = l.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteSoundFont(&bytes.Buffer{}, bank); err == nil {
+		t.Fatal("wrote a zone whose sampleID is not its last generator")
+	}
+
+	if err := bank.Hydra.ReorderGenerators(); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSoundFont(&bytes.Buffer{}, bank); err != nil {
+		t.Fatalf("after ReorderGenerators: %v", err)
+	}
+}
+
+func TestWriteSoundFontVerify(t *testing.T) {
+	bank := GenerateSineBank(2)
+	var plain, verified bytes.Buffer
+	if err := WriteSoundFont(&plain, bank); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSoundFont(&verified, bank, WithVerify()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain.Bytes(), verified.Bytes()) {
+		t.Error("WithVerify changed the bytes written")
+	}
+
+	// a file that does not hold what the bank does is caught
+	data := plain.Bytes()
+	smpl := bytes.Index(data, []byte("smpl"))
+	data[smpl+8+100] ^= 0xff
+	if err := verifyWritten(data, bank); err == nil {
+		t.Error("a changed sample point was not noticed")
+	}
+}
+
+func TestWriteSoundFontDeterministic(t *testing.T) {
+	for _, gen := range []func() *SoundFont{
+		func() *SoundFont { return GenerateSineBank(4) },
+		GeneratePathologicalBank,
+	} {
+		var a, b, c bytes.Buffer
+		bank := gen()
+		if err := WriteSoundFont(&a, bank); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteSoundFont(&b, bank); err != nil {
+			t.Fatal(err)
+		}
+		// an equal bank built separately
+		if err := WriteSoundFont(&c, gen()); err != nil {
+			t.Fatal(err)
+		}
+		if sha256.Sum256(a.Bytes()) != sha256.Sum256(b.Bytes()) {
+			t.Error("writing the same bank twice gave different files")
+		}
+		if sha256.Sum256(a.Bytes()) != sha256.Sum256(c.Bytes()) {
+			t.Error("writing equal banks gave different files")
+		}
+	}
+}
+
+func TestWriteSoundFontSizeLimit(t *testing.T) {
+	bank := GenerateSineBank(2)
+	var buf bytes.Buffer
+	if err := WriteSoundFont(&buf, bank); err != nil {
+		t.Fatal(err)
+	}
+	info, err := encodeInfo(bank.Info, bank.Samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hydra, err := encodeHydra(bank.Hydra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, riff, err := chunkSizes(len(info), len(hydra), len(bank.Samples.SamplesHigher), len(bank.Samples.SamplesLower))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(buf.Len()) - 8; riff != want {
+		t.Errorf("chunkSizes gives a RIFF size of %d, the file written has %d", riff, want)
+	}
+
+	// n points of 24-bit data take 3n bytes, plus a pad byte when n is odd,
+	// and the empty lists and chunk headers around them take 48 more
+	const fits = 1431655748
+	if _, _, riff, err := chunkSizes(0, 0, fits, fits); err != nil || riff != 48+3*fits {
+		t.Errorf("largest bank that fits: size %d, error %v", riff, err)
+	}
+	// 48 + 3*(fits+1) + 1 is just past the limit
+	if _, _, _, err := chunkSizes(0, 0, fits+1, fits+1); err == nil {
+		t.Error("no error for a bank past the 4 GB RIFF limit")
+	}
+}
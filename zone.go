@@ -0,0 +1,104 @@
+package sf
+
+import "fmt"
+
+// Zone is a single preset or instrument zone: the generators and modulators
+// that lie between two consecutive bag records.
+type Zone struct {
+	Generators []Generator
+	Modulators []Modulator
+
+	// Global is the global zone of the preset or instrument this zone belongs
+	// to. It is nil for the global zone itself, or when there is none.
+	Global *Zone
+}
+
+// terminal returns the value of the zone's final generator if it is op.
+func (z Zone) terminal(op SFGenerator) (int16, bool) {
+	if len(z.Generators) == 0 {
+		return 0, false
+	}
+	last := z.Generators[len(z.Generators)-1]
+	return last.GenAmount.AsInt16(), last.GenOper == op
+}
+
+// linkGlobal points every zone at the global zone, if there is one. Only the
+// first zone may be global, and it is global when it does not end with the
+// terminal generator op.
+func linkGlobal(zones []Zone, op SFGenerator) {
+	if len(zones) < 2 {
+		return
+	}
+	if _, ok := zones[0].terminal(op); ok {
+		return
+	}
+	for i := 1; i < len(zones); i++ {
+		zones[i].Global = &zones[0]
+	}
+}
+
+// PresetZones returns the zones of the preset at index i in Headers.
+func (h *SoundFontHydra) PresetZones(i int) ([]Zone, error) {
+	// the last header is the terminal record and owns no zones
+	if i < 0 || i+1 >= len(h.Headers) {
+		return nil, fmt.Errorf("preset index %d out of range", i)
+	}
+
+	start, end := int(h.Headers[i].PresetBagNdx), int(h.Headers[i+1].PresetBagNdx)
+	if start > end || end >= len(h.PBag) {
+		return nil, fmt.Errorf("preset %d has invalid bag range %d-%d", i, start, end)
+	}
+
+	zones := make([]Zone, 0, end-start)
+	for b := start; b < end; b++ {
+		genStart, genEnd := int(h.PBag[b].GenIndex), int(h.PBag[b+1].GenIndex)
+		modStart, modEnd := int(h.PBag[b].ModIndex), int(h.PBag[b+1].ModIndex)
+		if genStart > genEnd || genEnd > len(h.PresetGenerators) {
+			return nil, fmt.Errorf("preset bag %d has invalid generator range %d-%d", b, genStart, genEnd)
+		}
+		if modStart > modEnd || modEnd > len(h.PresetModulators) {
+			return nil, fmt.Errorf("preset bag %d has invalid modulator range %d-%d", b, modStart, modEnd)
+		}
+
+		zones = append(zones, Zone{
+			Generators: h.PresetGenerators[genStart:genEnd],
+			Modulators: h.PresetModulators[modStart:modEnd],
+		})
+	}
+	linkGlobal(zones, Gen_Instrument)
+
+	return zones, nil
+}
+
+// InstrumentZones returns the zones of the instrument at index i in Instuments.
+func (h *SoundFontHydra) InstrumentZones(i int) ([]Zone, error) {
+	// the last instrument is the terminal record and owns no zones
+	if i < 0 || i+1 >= len(h.Instuments) {
+		return nil, fmt.Errorf("instrument index %d out of range", i)
+	}
+
+	start, end := int(h.Instuments[i].InstBagNdx), int(h.Instuments[i+1].InstBagNdx)
+	if start > end || end >= len(h.IBag) {
+		return nil, fmt.Errorf("instrument %d has invalid bag range %d-%d", i, start, end)
+	}
+
+	zones := make([]Zone, 0, end-start)
+	for b := start; b < end; b++ {
+		genStart, genEnd := int(h.IBag[b].InstGenIndex), int(h.IBag[b+1].InstGenIndex)
+		modStart, modEnd := int(h.IBag[b].InstModIndex), int(h.IBag[b+1].InstModIndex)
+		if genStart > genEnd || genEnd > len(h.InstrumentGenerators) {
+			return nil, fmt.Errorf("instrument bag %d has invalid generator range %d-%d", b, genStart, genEnd)
+		}
+		if modStart > modEnd || modEnd > len(h.InstrumentModulators) {
+			return nil, fmt.Errorf("instrument bag %d has invalid modulator range %d-%d", b, modStart, modEnd)
+		}
+
+		zones = append(zones, Zone{
+			Generators: h.InstrumentGenerators[genStart:genEnd],
+			Modulators: h.InstrumentModulators[modStart:modEnd],
+		})
+	}
+	linkGlobal(zones, Gen_SampleID)
+
+	return zones, nil
+}
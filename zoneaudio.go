@@ -0,0 +1,22 @@
+package sf
+
+// ZoneAudio returns exactly the 16-bit PCM a region triggers: the data points
+// from its offset-adjusted start up to its end. The returned slice shares
+// memory with Samples.SamplesHigher. ROM samples have no data in the file
+// and return nil, as SampleData does.
+func (sf *SoundFont) ZoneAudio(r *Region) []int16 {
+	if sf.Samples == nil || r.Sample == nil || r.Sample.isROM() {
+		return nil
+	}
+
+	a := r.Addresses()
+	data := sf.Samples.SamplesHigher
+	start, end := int(a.Start), int(a.End)
+	if end > len(data) {
+		end = len(data)
+	}
+	if start > end {
+		start = end
+	}
+	return data[start:end]
+}
@@ -0,0 +1,26 @@
+package sf
+
+import "testing"
+
+func TestZoneAudio(t *testing.T) {
+	bank := GenerateSineBank(1)
+	regions, err := bank.Hydra.Regions(0, 69, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 1 {
+		t.Fatalf("got %d regions, want 1", len(regions))
+	}
+	r := regions[0]
+	higher, _ := bank.SampleData(*r.Sample)
+	if got := bank.ZoneAudio(&r); len(got) != len(higher) || &got[0] != &higher[0] {
+		t.Errorf("ZoneAudio returned %d points, want the sample's %d", len(got), len(higher))
+	}
+
+	rom := *r.Sample
+	rom.SampleType = SampleType_Rom_Mono
+	r.Sample = &rom
+	if got := bank.ZoneAudio(&r); got != nil {
+		t.Errorf("ZoneAudio returned %d points of smpl data for a ROM sample", len(got))
+	}
+}